@@ -0,0 +1,219 @@
+package formhandler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultBatchTokenHeader is the request header holding the batch token when
+	// Config.BatchTokenHeader is unset
+	defaultBatchTokenHeader = "X-Form-Batch-Token"
+
+	defaultMaxBatches   = 1000
+	defaultMaxBatchSize = megabyte * 10
+	defaultBatchTTL     = time.Hour
+)
+
+// BatchAccumulator merges forms submitted across multiple requests, such as the steps of a
+// multi-page form, keyed by a token the client sends with each request
+type BatchAccumulator interface {
+	// Append adds a successfully parsed form to the batch identified by token. A returned
+	// *ParseError rejects the request with its status and message, any other error with a 500.
+	// The form's temporary files are removed once the request is handled, so files must be
+	// copied to be kept.
+	Append(token string, form *Form) error
+	// Collect returns the merged form of every submission in the batch identified by token,
+	// or nil if there were none
+	Collect(token string) *Form
+}
+
+// MemoryBatchAccumulator is a BatchAccumulator holding batches in memory, safe for concurrent use.
+// Values of the same field across submissions are appended in submission order, and files are
+// copied into memory. As tokens are chosen by clients, batches are bounded in number, size and
+// age, the zero value of each limit using its default.
+type MemoryBatchAccumulator struct {
+	// MaxBatches is the number of batches held at once, starting a batch beyond it evicts the
+	// least recently appended one. Defaults to 1000.
+	MaxBatches int
+	// MaxBatchSize is the number of bytes of values and files a batch may hold, appending beyond
+	// it rejects the request with a 413. Defaults to 10MB.
+	MaxBatchSize int64
+	// TTL is how long a batch is kept after its last append before it's evicted. Defaults to
+	// an hour.
+	TTL time.Duration
+
+	mu      sync.Mutex
+	batches map[string]*memoryBatch
+}
+
+// memoryBatch is a batch held by a MemoryBatchAccumulator
+type memoryBatch struct {
+	form    *Form
+	size    int64
+	updated time.Time
+}
+
+// NewMemoryBatchAccumulator returns an empty MemoryBatchAccumulator with the default limits
+func NewMemoryBatchAccumulator() *MemoryBatchAccumulator {
+	return &MemoryBatchAccumulator{batches: make(map[string]*memoryBatch)}
+}
+
+// Append merges form into the batch identified by token, copying its files into memory
+func (mba *MemoryBatchAccumulator) Append(token string, form *Form) error {
+	maxBatchSize := mba.MaxBatchSize
+	if maxBatchSize == 0 {
+		maxBatchSize = defaultMaxBatchSize
+	}
+
+	size := formSize(form)
+	if size > maxBatchSize {
+		return &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Batch is too large"}
+	}
+	files := make(map[string][]*multipart.FileHeader, len(form.Files))
+	for field, headers := range form.Files {
+		for _, header := range headers {
+			copied, err := memoryFileHeader(field, header)
+			if err != nil {
+				return err
+			}
+			files[field] = append(files[field], copied)
+		}
+	}
+
+	mba.mu.Lock()
+	defer mba.mu.Unlock()
+
+	now := time.Now()
+	mba.evictExpired(now)
+	batch, ok := mba.batches[token]
+	// the size is checked before a batch is started, so a rejected form doesn't leave an empty
+	// batch behind or evict another
+	if ok && batch.size+size > maxBatchSize {
+		return &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Batch is too large"}
+	}
+	if !ok {
+		mba.evictOldest()
+		batch = &memoryBatch{form: &Form{Values: make(map[string][]string), Files: make(map[string][]*multipart.FileHeader)}}
+		if mba.batches == nil {
+			mba.batches = make(map[string]*memoryBatch)
+		}
+		mba.batches[token] = batch
+	}
+	batch.size += size
+	batch.updated = now
+
+	for field, values := range form.Values {
+		batch.form.Values[field] = append(batch.form.Values[field], values...)
+	}
+	for field, headers := range files {
+		batch.form.Files[field] = append(batch.form.Files[field], headers...)
+	}
+	return nil
+}
+
+// Collect returns the merged form of the batch identified by token and removes the batch
+func (mba *MemoryBatchAccumulator) Collect(token string) *Form {
+	mba.mu.Lock()
+	defer mba.mu.Unlock()
+
+	mba.evictExpired(time.Now())
+	batch, ok := mba.batches[token]
+	if !ok {
+		return nil
+	}
+	delete(mba.batches, token)
+	return batch.form
+}
+
+// evictExpired removes the batches last appended to more than TTL before now
+func (mba *MemoryBatchAccumulator) evictExpired(now time.Time) {
+	ttl := mba.TTL
+	if ttl == 0 {
+		ttl = defaultBatchTTL
+	}
+	for token, batch := range mba.batches {
+		if now.Sub(batch.updated) > ttl {
+			delete(mba.batches, token)
+		}
+	}
+}
+
+// evictOldest removes the least recently appended batch when MaxBatches are held
+func (mba *MemoryBatchAccumulator) evictOldest() {
+	maxBatches := mba.MaxBatches
+	if maxBatches == 0 {
+		maxBatches = defaultMaxBatches
+	}
+	if len(mba.batches) < maxBatches {
+		return
+	}
+
+	var oldestToken string
+	var oldest *memoryBatch
+	for token, batch := range mba.batches {
+		if oldest == nil || batch.updated.Before(oldest.updated) {
+			oldestToken, oldest = token, batch
+		}
+	}
+	delete(mba.batches, oldestToken)
+}
+
+// formSize returns the number of bytes of the values and files of form
+func formSize(form *Form) int64 {
+	var size int64
+	for field, values := range form.Values {
+		for _, value := range values {
+			size += int64(len(field) + len(value))
+		}
+	}
+	for _, headers := range form.Files {
+		for _, header := range headers {
+			size += header.Size
+		}
+	}
+	return size
+}
+
+// memoryFileHeader returns a copy of header with its content held in memory, so it can be
+// opened after the request's temporary files are removed
+func memoryFileHeader(field string, header *multipart.FileHeader) (*multipart.FileHeader, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("formhandler: opening batched file %q: %w", header.Filename, err)
+	}
+	defer file.Close()
+
+	// a FileHeader's content can only be set by reading it from a multipart body
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	partHeader := make(textproto.MIMEHeader, len(header.Header))
+	for key, values := range header.Header {
+		partHeader[key] = values
+	}
+	partHeader.Set("Content-Disposition",
+		fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(field), escapeQuotes(header.Filename)))
+	part, err := mw.CreatePart(partHeader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, fmt.Errorf("formhandler: copying batched file %q: %w", header.Filename, err)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	// the limit only bounds what's held in memory, which is the whole copy
+	copied, err := multipart.NewReader(&buf, mw.Boundary()).ReadForm(int64(buf.Len()) + 1)
+	if err != nil {
+		return nil, err
+	}
+	return copied.File[field][0], nil
+}
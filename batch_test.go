@@ -0,0 +1,156 @@
+package formhandler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchAccumulator(t *testing.T) {
+	batches := NewMemoryBatchAccumulator()
+	cfg := defaultConfig()
+	cfg.BatchAccumulator = batches
+
+	steps := []url.Values{
+		{"name": {"charlie"}, "choices": {"1"}},
+		{"choices": {"4"}, "email": {"charlie@example.com"}},
+	}
+	for _, step := range steps {
+		r, err := constructURLEncodedForm(step)
+		assert.NoError(t, err)
+		r.Header.Set("X-Form-Batch-Token", "session-1")
+		_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+		assert.NoError(t, err)
+	}
+
+	// a request without a token isn't part of any batch
+	r, err := constructURLEncodedForm(url.Values{"name": {"other"}})
+	assert.NoError(t, err)
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+
+	form := batches.Collect("session-1")
+	if assert.NotNil(t, form) {
+		assert.Equal(t, map[string][]string{
+			"name":    {"charlie"},
+			"choices": {"1", "4"},
+			"email":   {"charlie@example.com"},
+		}, form.Values)
+	}
+	assert.Nil(t, batches.Collect("session-1"), "collecting removes the batch")
+}
+
+func TestBatchAccumulator_CustomHeader(t *testing.T) {
+	batches := NewMemoryBatchAccumulator()
+	cfg := defaultConfig()
+	cfg.BatchAccumulator = batches
+	cfg.BatchTokenHeader = "X-Wizard-Session"
+
+	r, err := constructJSONEncodedForm(`{"name": "charlie"}`)
+	assert.NoError(t, err)
+	r.Header.Set("X-Wizard-Session", "abc")
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+
+	form := batches.Collect("abc")
+	if assert.NotNil(t, form) {
+		assert.Equal(t, map[string][]string{"name": {"charlie"}}, form.Values)
+	}
+}
+
+func TestBatchAccumulator_FilesOutliveRequest(t *testing.T) {
+	batches := NewMemoryBatchAccumulator()
+	cfg := defaultConfig()
+	cfg.BatchAccumulator = batches
+	// written to a temporary file removed once the request is handled
+	cfg.MaxMemory = 1
+
+	r, err := constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "notes.txt", value: "some notes"}})
+	assert.NoError(t, err)
+	r.Header.Set("X-Form-Batch-Token", "session-1")
+	form, err := cfg.GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.NoError(t, form.Cleanup())
+
+	batch := batches.Collect("session-1")
+	if assert.NotNil(t, batch) && assert.Len(t, batch.Files["file1"], 1) {
+		header := batch.Files["file1"][0]
+		assert.Equal(t, "notes.txt", header.Filename)
+		file, err := header.Open()
+		if assert.NoError(t, err) {
+			content, err := io.ReadAll(file)
+			assert.NoError(t, err)
+			assert.Equal(t, "some notes", string(content))
+			file.Close()
+		}
+	}
+}
+
+func TestBatchAccumulator_Limits(t *testing.T) {
+	batches := NewMemoryBatchAccumulator()
+	batches.MaxBatches = 2
+	batches.MaxBatchSize = 20
+	cfg := defaultConfig()
+	cfg.BatchAccumulator = batches
+
+	appendForm := func(token, name string) error {
+		r, err := constructURLEncodedForm(url.Values{"name": {name}})
+		assert.NoError(t, err)
+		r.Header.Set("X-Form-Batch-Token", token)
+		_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+		return err
+	}
+
+	// a batch can't grow beyond MaxBatchSize
+	assert.NoError(t, appendForm("session-1", "charlie"))
+	err := appendForm("session-1", "charlotte")
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, pe.Status)
+	}
+
+	// a form too large to start a batch leaves no empty batch behind, and evicts none
+	err = appendForm("session-2", strings.Repeat("a", 20))
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, pe.Status)
+	}
+	assert.Len(t, batches.batches, 1)
+	assert.NotContains(t, batches.batches, "session-2")
+
+	// starting a batch beyond MaxBatches evicts the least recently appended one
+	assert.NoError(t, appendForm("session-2", "alice"))
+	assert.NoError(t, appendForm("session-3", "bob"))
+	assert.Nil(t, batches.Collect("session-1"))
+	assert.NotNil(t, batches.Collect("session-2"))
+
+	// batches expire TTL after their last append
+	batches.TTL = time.Millisecond
+	time.Sleep(5 * time.Millisecond)
+	assert.Nil(t, batches.Collect("session-3"))
+}
+
+func TestBatchAccumulator_NotAppendedAfterTimeout(t *testing.T) {
+	batches := NewMemoryBatchAccumulator()
+	cfg := defaultConfig()
+	cfg.BatchAccumulator = batches
+	cfg.ParseTimeout = 20 * time.Millisecond
+
+	r, err := http.NewRequest(http.MethodPost, "/", &tricklingReader{content: "name=charlie", delay: 10 * time.Millisecond})
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Form-Batch-Token", "session-1")
+
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusRequestTimeout, pe.Status)
+	}
+	assert.Nil(t, batches.Collect("session-1"))
+}
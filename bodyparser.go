@@ -0,0 +1,106 @@
+package formhandler
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// BodyParser parses a request body of a Content-Type registered with RegisterBodyParser into form
+// values, such as the parsers of the cbor, yaml and protobuf subpackages. The body is already
+// decompressed and limited to MaxFormSize bytes, and reading past the limit rejects the request
+// with a 413 whatever is returned. The values returned are checked and transformed by the same
+// options as those of a JSON body, so most parsers decode the body and return Config.FlattenMap
+// of the decoded document.
+type BodyParser func(cfg Config, r *http.Request, body io.Reader) (map[string][]string, *ParseError)
+
+// bodyParsers holds the parsers registered with RegisterBodyParser, keyed by content type
+var bodyParsers = struct {
+	sync.RWMutex
+	parsers map[string]BodyParser
+}{parsers: make(map[string]BodyParser)}
+
+// RegisterBodyParser registers parse as the parser of request bodies whose Content-Type media
+// type is contentType, e.g. "application/cbor". Parameters of the Content-Type header, such as
+// the proto parameter of "application/protobuf; proto=signup.Signup", are left for parse to
+// read. It's usually called from the init function of a subpackage imported for its side effect:
+//
+//	import _ "github.com/charlesworth/formhandler/cbor"
+//
+// Registering a content type again replaces its parser. It panics for the content types parsed
+// by this package, which can't be replaced.
+func RegisterBodyParser(contentType string, parse BodyParser) {
+	if containsString(supportedContentTypes, contentType) || contentType == headerValTextPlain {
+		panic(fmt.Sprintf("formhandler: RegisterBodyParser given built in content type %s", contentType))
+	}
+
+	bodyParsers.Lock()
+	defer bodyParsers.Unlock()
+	bodyParsers.parsers[contentType] = parse
+}
+
+func lookupBodyParser(contentType string) (BodyParser, bool) {
+	bodyParsers.RLock()
+	defer bodyParsers.RUnlock()
+	parse, ok := bodyParsers.parsers[contentType]
+	return parse, ok
+}
+
+// registeredContentType returns the registered content type a Content-Type header with
+// parameters is the media type of, or "" if it's none of them
+func registeredContentType(header string) string {
+	bodyParsers.RLock()
+	defer bodyParsers.RUnlock()
+
+	for contentType := range bodyParsers.parsers {
+		// the header is only parsed when it could be a registered type, as parsing allocates on
+		// every request
+		if strings.HasPrefix(header, contentType) && len(header) > len(contentType) {
+			if mediaType, _, err := mime.ParseMediaType(header); err == nil && mediaType == contentType {
+				return contentType
+			}
+		}
+	}
+	return ""
+}
+
+// isSupportedContentType reports whether requests of the content type returned by getContentType
+// have a parser, ignoring text/plain which is only parsed with ParsePlainTextForms
+func isSupportedContentType(contentType string) bool {
+	if containsString(supportedContentTypes, contentType) {
+		return true
+	}
+	_, ok := lookupBodyParser(contentType)
+	return ok
+}
+
+// parseRegistered parses the request body with the BodyParser registered for its content type
+func (cfg Config) parseRegistered(parse BodyParser, r *http.Request, reader io.Reader) (results map[string][]string, err *ParseError) {
+	body := &readErrorRecorder{Reader: reader}
+	results, err = parse(cfg, r, body)
+	// parsers can report a read error as badly-formed content
+	if body.err != nil && isRequestTooLarge(body.err) {
+		return nil, &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// readErrorRecorder records the last error other than io.EOF returned by reading a body
+type readErrorRecorder struct {
+	io.Reader
+	err error
+}
+
+func (rer *readErrorRecorder) Read(p []byte) (int, error) {
+	n, err := rer.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		rer.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,108 @@
+package formhandler
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterBodyParser(t *testing.T) {
+	// a parser of "key=value" lines
+	RegisterBodyParser("application/x-test-lines", func(cfg Config, r *http.Request, body io.Reader) (map[string][]string, *ParseError) {
+		content, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Request body could not be read"}
+		}
+		document := make(map[string]interface{})
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			key, value, _ := strings.Cut(line, "=")
+			document[key] = value
+		}
+		return cfg.FlattenMap(document, "Lines document")
+	})
+
+	var registeredTests = []struct {
+		testName             string
+		contentType          string
+		body                 string
+		expectedValuesOutput map[string][]string
+		expectedStatus       int
+	}{
+		{"registered type", "application/x-test-lines", "field1=value1\nfield2=value2", map[string][]string{"field1": {"value1"}, "field2": {"value2"}}, 0},
+		{"registered type with parameters", "application/x-test-lines; charset=utf-8", "field1=value1", map[string][]string{"field1": {"value1"}}, 0},
+		{"value rules apply", "application/x-test-lines", "field1=", nil, http.StatusBadRequest},
+		{"too large", "application/x-test-lines", "field1=" + strings.Repeat("a", 2*megabyte), nil, http.StatusRequestEntityTooLarge},
+		{"unregistered type", "application/x-test-other", "field1=value1", nil, http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range registeredTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+
+			results, _, err := GetFormContent(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+			if tt.expectedStatus == 0 {
+				assert.NoError(t, err)
+			} else {
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, tt.expectedStatus, pe.Status)
+				}
+			}
+		})
+	}
+
+	// the built in parsers can't be replaced
+	assert.Panics(t, func() { RegisterBodyParser("application/json", nil) })
+}
+
+func TestJSONValidator(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.JSONValidator = func(object map[string]interface{}) error {
+		if _, ok := object["name"]; !ok {
+			return &ParseError{Status: http.StatusUnprocessableEntity, Msg: "Name is required"}
+		}
+		if _, ok := object["admin"]; ok {
+			return errors.New("admin can't be set")
+		}
+		return nil
+	}
+
+	var validatorTests = []struct {
+		testName             string
+		body                 string
+		expectedValuesOutput map[string][]string
+		expectedStatus       int
+		expectedMsg          string
+	}{
+		{"valid", `{"name": "charlie"}`, map[string][]string{"name": {"charlie"}}, 0, ""},
+		{"parse error", `{"email": "charlie@example.com"}`, nil, http.StatusUnprocessableEntity, "Name is required"},
+		{"other error", `{"name": "charlie", "admin": "true"}`, nil, http.StatusBadRequest, "JSON object is invalid"},
+	}
+
+	for _, tt := range validatorTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructJSONEncodedForm(tt.body)
+			assert.NoError(t, err)
+
+			results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+			if tt.expectedStatus == 0 {
+				assert.NoError(t, err)
+			} else {
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, tt.expectedStatus, pe.Status)
+					assert.Equal(t, tt.expectedMsg, pe.Msg)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Package cbor adds application/cbor request bodies to formhandler, for clients such as embedded
+// devices without a JSON encoder. Importing it registers the parser:
+//
+//	import _ "github.com/charlesworth/formhandler/cbor"
+//
+// A body must be a single CBOR map with string keys, and follows the same value rules as JSON.
+//
+// CBOR bodies used to be parsed by the formhandler package itself. They're now rejected with a
+// 415 unless this package is imported, so programs accepting CBOR must add the import above.
+package cbor
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/charlesworth/formhandler"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// ContentType is the Content-Type of the request bodies parsed by this package
+const ContentType = "application/cbor"
+
+// decMode decodes CBOR maps into map[string]interface{} so they can be flattened with the
+// same rules as JSON objects
+var decMode, _ = cbor.DecOptions{
+	DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+}.DecMode()
+
+func init() {
+	formhandler.RegisterBodyParser(ContentType, parse)
+}
+
+// parse parses a single CBOR map, applying the same value rules as JSON
+func parse(cfg formhandler.Config, r *http.Request, body io.Reader) (map[string][]string, *formhandler.ParseError) {
+	dec := decMode.NewDecoder(body)
+	cborContent := map[string]interface{}{}
+	decodeErr := dec.Decode(&cborContent)
+	if decodeErr != nil {
+		var unmarshalTypeError *cbor.UnmarshalTypeError
+
+		switch {
+		case errors.Is(decodeErr, io.EOF):
+			return nil, &formhandler.ParseError{Status: http.StatusBadRequest, Msg: "Request body must not be empty"}
+
+		case errors.As(decodeErr, &unmarshalTypeError):
+			return nil, &formhandler.ParseError{Status: http.StatusBadRequest, Msg: "Request body must contain a CBOR map with string keys"}
+
+		default:
+			return nil, &formhandler.ParseError{Status: http.StatusBadRequest, Msg: "Request body contains badly-formed CBOR"}
+		}
+	}
+
+	var trailing interface{}
+	if secondDecodeErr := dec.Decode(&trailing); secondDecodeErr != io.EOF {
+		return nil, &formhandler.ParseError{Status: http.StatusBadRequest, Msg: "Request body must only contain a single CBOR map"}
+	}
+
+	return cfg.FlattenMap(cborContent, "CBOR map")
+}
@@ -0,0 +1,76 @@
+package cbor
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/charlesworth/formhandler"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	mustMarshal := func(v interface{}) []byte {
+		b, err := cbor.Marshal(v)
+		assert.NoError(t, err)
+		return b
+	}
+
+	var formContentTests = []struct {
+		testName             string
+		body                 []byte
+		coerceScalars        bool
+		expectedValuesOutput map[string][]string
+		expectedStatus       int
+	}{
+		{
+			"string and string array fields",
+			mustMarshal(map[string]interface{}{"field1": "value1", "field2": []string{"value21", "value22"}}),
+			false,
+			map[string][]string{"field1": {"value1"}, "field2": {"value21", "value22"}},
+			0,
+		},
+		{
+			"coerced scalars",
+			mustMarshal(map[string]interface{}{"age": 30, "offset": -2, "height": 1.5, "member": true}),
+			true,
+			map[string][]string{"age": {"30"}, "offset": {"-2"}, "height": {"1.5"}, "member": {"true"}},
+			0,
+		},
+		{"integer without coercion", mustMarshal(map[string]interface{}{"age": 30}), false, nil, http.StatusBadRequest},
+		{"empty string", mustMarshal(map[string]interface{}{"field1": ""}), false, nil, http.StatusBadRequest},
+		{"nested map", mustMarshal(map[string]interface{}{"field1": map[string]string{"a": "b"}}), false, nil, http.StatusBadRequest},
+		{"empty map", mustMarshal(map[string]interface{}{}), false, nil, http.StatusBadRequest},
+		{"not a map", mustMarshal([]string{"a"}), false, nil, http.StatusBadRequest},
+		{"non string keys", mustMarshal(map[int]string{1: "a"}), false, nil, http.StatusBadRequest},
+		{"multiple maps", append(mustMarshal(map[string]string{"a": "a"}), mustMarshal(map[string]string{"b": "b"})...), false, nil, http.StatusBadRequest},
+		{"empty body", []byte{}, false, nil, http.StatusBadRequest},
+		{"malformed", []byte{0xbf, 0x61}, false, nil, http.StatusBadRequest},
+		{"too large", mustMarshal(map[string]string{"field1": strings.Repeat("a", 2_000_000)}), false, nil, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range formContentTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			var cfg formhandler.Config
+			cfg.CoerceScalars = tt.coerceScalars
+
+			r, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			assert.NoError(t, err)
+			r.Header.Set("Content-Type", "application/cbor")
+
+			results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+			if tt.expectedStatus == 0 {
+				assert.NoError(t, err)
+			} else {
+				var pe *formhandler.ParseError
+				assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+				assert.Equal(t, tt.expectedStatus, pe.Status)
+			}
+		})
+	}
+}
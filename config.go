@@ -0,0 +1,354 @@
+package formhandler
+
+import (
+	"mime/multipart"
+	"net/url"
+	"time"
+)
+
+// Config holds the limits and options used when parsing a form request. Every field is
+// optional, the zero value of each size limit uses its default and the zero value of every
+// other option leaves it disabled.
+type Config struct {
+	// MaxFormSize is the maximum size in bytes of a request without files, i.e. every
+	// Content-Type other than multipart/form-data. Defaults to 1MB.
+	MaxFormSize int64
+	// MaxFormWithFilesSize is the maximum size in bytes of a multipart/form-data request,
+	// which can have files attached. Defaults to 10MB.
+	MaxFormWithFilesSize int64
+	// MaxMemory is the number of bytes of a multipart/form-data request's file parts stored in
+	// memory, with the remainder stored on disk in temporary files. Defaults to 10MB.
+	MaxMemory int64
+	// MaxPartHeaderSize is the maximum size in bytes of the header block of each part of a
+	// multipart/form-data request. Forms with larger part headers, such as thousands of custom
+	// header lines, are rejected with a 400. Defaults to 16KB.
+	MaxPartHeaderSize int64
+
+	// BufferBody reads the whole request body into memory, up to the size limit of its
+	// Content-Type, before parsing it, and leaves r.Body reading the buffered bytes from the start
+	// once parsing returns, so later handlers can read the raw body again. Bodies over the limit
+	// are rejected with a 413 before being fully buffered.
+	BufferBody bool
+
+	// MaxDecodedValueBytes is the maximum size in bytes of a single URL encoded form value
+	// after it has been percent-decoded. Requests exceeding it are rejected with a 413,
+	// zero disables the check.
+	MaxDecodedValueBytes int
+
+	// AllowSemicolonSeparators accepts ";" as well as "&" between the fields of URL encoded
+	// bodies, as sent by some legacy clients. By default net/http rejects them with a 400.
+	AllowSemicolonSeparators bool
+
+	// IncludeQueryParams merges the URL query string values into the results of URL encoded
+	// requests like http.Request.ParseForm, each field's body values coming before its query
+	// string values. By default only the body is parsed, so a query string can't add fields to
+	// the form, matching multipart requests.
+	IncludeQueryParams bool
+
+	// ExpandJSONArrayValues expands URL encoded form fields with a single value holding a JSON
+	// array of strings, e.g. tags=["a","b"], into the array's values. Values starting with "["
+	// that aren't a JSON array of strings are rejected with a 400, other values are unchanged.
+	ExpandJSONArrayValues bool
+
+	// MaxFields is the maximum number of distinct value and file fields a form may contain,
+	// counted after empty URL encoded and multipart fields are removed. Forms with more fields
+	// are rejected with a 400, zero disables the check.
+	MaxFields int
+
+	// MaxValueLength is the maximum length in bytes of any single value, checking every value
+	// of fields with multiple values. Forms with a longer value are rejected with a 400 naming
+	// the field, zero disables the check.
+	MaxValueLength int
+
+	// RequireContentLength rejects multipart requests without a Content-Length header, such as
+	// chunked uploads, with a 411, and those declaring a Content-Length over the size limit with a
+	// 413, before any of the body is read
+	RequireContentLength bool
+
+	// MaxFiles is the maximum number of files a multipart form may contain across all of its
+	// fields. Forms with more files are rejected with a 400, zero disables the check.
+	MaxFiles int
+
+	// CollectAllErrors reports every invalid field of a JSON, CBOR or YAML body rather than only
+	// the first found, in a single 400 whose message lists each field's error in alphabetical
+	// order of field, so clients can fix all of them before resubmitting.
+	CollectAllErrors bool
+
+	// MaxArrayLen is the maximum number of values an array field of a JSON, CBOR or YAML body
+	// may contain, longer arrays are rejected with a 400. It's checked once the body has been
+	// decoded, so it limits the values returned rather than the memory used decoding, which is
+	// bounded by MaxFormSize. Zero disables the check.
+	MaxArrayLen int
+
+	// OnEmptyResult decides what happens when a request parses without error but contains
+	// no values or files, by default the empty results are returned.
+	OnEmptyResult EmptyResultAction
+
+	// ContentTypeAliases maps lowercase media types, without parameters, onto the supported
+	// Content-Type they should be parsed as, e.g. {"application/vnd.myapp+json": "application/json"}.
+	// The request's Content-Type header is rewritten to the supported type, keeping any parameters
+	// such as a multipart boundary.
+	ContentTypeAliases map[string]string
+
+	// SniffContentType guesses the Content-Type of requests without a Content-Type header from the
+	// first byte of the body that isn't whitespace, "{" or "[" being JSON and any other printable
+	// character a URL encoded form. Bodies that are empty or start with another byte fall back to
+	// DefaultContentType, or are rejected with a 415.
+	SniffContentType bool
+
+	// DefaultContentType is the Content-Type assumed for requests without a Content-Type header,
+	// e.g. "application/json" for a JSON only API used with curl. The header is set on the request
+	// so the body is parsed as if the client had sent it. When empty such requests are rejected
+	// with a 415.
+	DefaultContentType string
+
+	// RequireMultipartForFiles rejects requests that are not multipart/form-data encoded with a
+	// 400, for endpoints expecting file uploads where JSON or URL encoded bodies would silently
+	// parse without any files. It only applies when an option checking files is set: MaxFiles,
+	// AllowedFileExtensions, AllowedFileContentTypes, FileSignatureChecks, RejectEmptyFiles or
+	// ScanFile.
+	RequireMultipartForFiles bool
+
+	// CaseInsensitiveKeys lowercases every value and file field name, so fields sent as "Email"
+	// and "email" are both returned under "email" with their values concatenated. Fields must be
+	// looked up, and listed in options such as AllowedFields, by their lowercase name.
+	CaseInsensitiveKeys bool
+
+	// FieldRename maps the field names sent by clients to the names returned in the results and
+	// files, e.g. {"first_name": "firstName"}, applied after CaseInsensitiveKeys and before every
+	// check naming fields. Fields renamed to the same name have their values concatenated in the
+	// alphabetical order of the original names.
+	FieldRename map[string]string
+
+	// DropUnmapped removes fields missing from FieldRename from the results and files, rather
+	// than keeping them under their original name. It has no effect without FieldRename.
+	DropUnmapped bool
+
+	// RejectNullBytes rejects requests with a 400 naming the field when any value contains a null
+	// byte, which databases such as Postgres can't store in text columns.
+	RejectNullBytes bool
+
+	// RequireValidUTF8 rejects requests with a 400 naming the field when any value isn't valid
+	// UTF-8. JSON bodies are always valid UTF-8, so this applies to the other formats.
+	RequireValidUTF8 bool
+
+	// TrimSpace removes leading and trailing whitespace from every value before ValueTransform is
+	// called. Values left empty are removed from URL encoded, multipart, CSV and text/plain forms,
+	// and rejected with a 400 in other formats, unless KeepEmptyFields is set.
+	TrimSpace bool
+
+	// ValueTransform, when set, is called with every value of every field, including each value
+	// of fields with multiple values, and the value is replaced with the result. It can be used
+	// to trim, escape or strip characters from values in one place. Values are transformed before
+	// empty fields are removed, so values transformed to an empty string are removed too. An
+	// error rejects the request with a 400 naming the field, or with the status and message of
+	// a *ParseError.
+	ValueTransform func(field, value string) (string, error)
+
+	// KeepEmptyFields keeps fields whose only value is an empty string in the results, for forms
+	// where submitting an empty value is meaningful, e.g. to clear a previously set value. By
+	// default they are removed from URL encoded, multipart, CSV and text/plain forms, and rejected
+	// with a 400 in JSON, XML, CBOR and YAML.
+	KeepEmptyFields bool
+
+	// WarnDroppedFields adds a Warning header to the response listing the form fields that were
+	// removed from the results for being empty, giving clients visibility of silently dropped fields.
+	WarnDroppedFields bool
+
+	// DecodeDataURIFields names URL encoded and multipart form fields whose values are data URIs,
+	// e.g. "data:image/png;base64,iVBORw0KGgo...". The decoded content is returned in the files
+	// map under the field name instead of in the results, values that are not data URIs are
+	// rejected with a 400.
+	DecodeDataURIFields []string
+
+	// JSONFileFields names JSON fields whose values are base64 encoded files, for clients unable
+	// to send multipart requests. The decoded content is returned in the files map under the
+	// field name instead of in the results, with its content type sniffed from the content.
+	// Values that are not valid base64 are rejected with a 400.
+	JSONFileFields []string
+
+	// JSONMultipartFields names multipart value fields holding a JSON object, such as a "metadata"
+	// field sent alongside files with JS FormData. Each object's fields are added to the results
+	// following the JSON value rules, namespaced as "metadata.title" unless
+	// FlattenJSONMultipartFields is set, in place of the field holding it. Values that are not a
+	// JSON object, or that set a field already in the form, are rejected with a 400.
+	JSONMultipartFields []string
+
+	// FlattenJSONMultipartFields adds the fields of JSONMultipartFields objects to the results
+	// under their own names, e.g. "title", rather than namespaced under the multipart field.
+	FlattenJSONMultipartFields bool
+
+	// DateFields maps field names to the Go time layout their values must match, e.g.
+	// {"birthday": "2006-01-02"}. Values failing to parse are rejected with a 400.
+	DateFields map[string]string
+
+	// OnMemoryOverflow decides what happens when a multipart form is larger than MaxMemory, by
+	// default files are spilled to temporary files on disk. MemoryOverflowReject instead rejects
+	// the request with a 413, for environments without a writable filesystem. The body is then
+	// limited to MaxMemory as it is read, so no temporary file is ever created; raise MaxMemory
+	// to accept larger uploads in memory.
+	OnMemoryOverflow MemoryOverflowAction
+
+	// AllowedMethods lists the HTTP methods forms may be submitted with, requests using any other
+	// method are rejected with a 405. When nil every method except GET, HEAD and DELETE is allowed.
+	AllowedMethods []string
+
+	// MethodContentTypes maps HTTP methods to the Content-Types allowed for them, e.g.
+	// {"PATCH": {"application/json"}}. Requests using a listed method with any other Content-Type
+	// are rejected with a 400, methods missing from the map accept every supported Content-Type.
+	MethodContentTypes map[string][]string
+
+	// EmptyArrayStrategy decides how JSON fields containing an empty array are handled, by
+	// default they are rejected with a 400.
+	EmptyArrayStrategy EmptyArrayStrategy
+
+	// AllowedFileContentTypes lists the media types uploaded files may have, e.g. "image/png".
+	// The type is sniffed from the first 512 bytes of each file with http.DetectContentType
+	// rather than trusting the part's Content-Type header, other files are rejected with a 415.
+	AllowedFileContentTypes []string
+
+	// AllowedFileExtensions lists the lowercase extensions uploaded files may have, e.g. ".pdf".
+	// Each filename's extension is lowercased before the check, files with any other extension or
+	// none are rejected with a 415. Combine with AllowedFileContentTypes to also check content.
+	AllowedFileExtensions []string
+
+	// MinBodySize is the minimum number of bytes a request body must contain, smaller bodies are
+	// rejected with a 400. Zero disables the check.
+	MinBodySize int64
+
+	// ParsePlainTextForms accepts the HTML text/plain form encoding, where each field is sent
+	// as an unescaped "name=value" line. Without it text/plain requests are rejected with a 415.
+	ParsePlainTextForms bool
+
+	// CoerceScalars accepts JSON numbers and booleans, including inside arrays, storing them as
+	// their string representation, e.g. 30 becomes "30" and true becomes "true". JSON nulls are
+	// still rejected.
+	CoerceScalars bool
+
+	// FlattenSingleValues makes Config.ToMapInterface convert fields with a single value to a
+	// string rather than a []string, as the package level ToMapInterface always does
+	FlattenSingleValues bool
+
+	// FileSignatureChecks maps lowercase file extensions to the magic bytes files with that
+	// extension must start with, e.g. {".pdf": []byte("%PDF")}. Files not matching the signature
+	// of their extension are rejected with a 400, extensions missing from the map are unchecked.
+	FileSignatureChecks map[string][]byte
+
+	// HashFiles computes the SHA-256 checksum of every accepted file, returned in
+	// Form.FileHashes. Each file is read once, and can still be opened or saved afterwards.
+	HashFiles bool
+
+	// RejectEmptyFiles rejects multipart forms with a file of zero bytes with a 400, or with
+	// RejectFilesIndividually set removes the file, and its field if it has no other files
+	RejectEmptyFiles bool
+
+	// ScanFile, when set, is called with every uploaded file opened for reading, such as to send
+	// it to a virus scanner, after the other file checks pass. A file it returns an error for
+	// rejects the request with a 422 naming the file, the error itself is kept in the
+	// ParseError's Err and never sent to the client. Files can still be opened from the start
+	// afterwards.
+	ScanFile func(field string, f multipart.File, header *multipart.FileHeader) error
+
+	// RejectFilesIndividually removes files failing RejectEmptyFiles, AllowedFileExtensions,
+	// AllowedFileContentTypes, FileSignatureChecks or ScanFile from the files, listing them with
+	// the reason in Form.RejectedFiles, instead of rejecting the whole request. The caller decides
+	// whether to accept the rest.
+	RejectFilesIndividually bool
+
+	// BatchAccumulator, when set, is given every successfully parsed form of a request carrying
+	// a batch token in the BatchTokenHeader request header, merging submissions spanning multiple
+	// requests. An error from it rejects the request. BatchTokenHeader defaults to
+	// "X-Form-Batch-Token".
+	BatchAccumulator BatchAccumulator
+	BatchTokenHeader string
+
+	// RejectUnsafeKeyRunes rejects requests with a 400 when a field name contains Unicode control
+	// or format characters (categories Cc and Cf), such as zero-width spaces and bidirectional
+	// overrides, which can be used to spoof field names.
+	RejectUnsafeKeyRunes bool
+
+	// AllowedFields, when set, lists every value and file field a request may contain. Requests
+	// with other fields are rejected with a 400 naming them. Empty fields removed from URL encoded
+	// and multipart forms are not checked.
+	AllowedFields []string
+
+	// RecordFileOrder records the order files appear in the multipart body, for Form.OrderedFiles.
+	// Recording reads the part headers of the body a second time as it is parsed.
+	RecordFileOrder bool
+
+	// ParseTimeout is the longest parsing a request may take, requests taking longer, such as slow
+	// clients trickling a body, are rejected with a 408. Zero disables the timeout. It's set as the
+	// read deadline of the connection when the ResponseWriter supports it, as those of net/http
+	// servers since Go 1.20 do, so a read blocked on a stalled client fails once it passes;
+	// otherwise it's only checked between reads of the body. The connection of a rejected request
+	// is closed rather than kept alive.
+	ParseTimeout time.Duration
+
+	// PerPartTimeout is the longest StreamMultipart may take to read each part of a multipart
+	// body, from its headers to its last byte, so a slow client can't trickle a single part while
+	// the rest of the stream moves. A part taking longer is rejected with a 408. It's checked
+	// between reads of the body, so a read blocked on a stalled client is left to ParseTimeout or
+	// the server's timeouts. Zero disables the timeout.
+	PerPartTimeout time.Duration
+
+	// Metrics, when set, observes the content type, body size, duration and error of every
+	// request parsed
+	Metrics Metrics
+
+	// ForwardTo is the downstream URL Config.Forward POSTs parsed forms to
+	ForwardTo *url.URL
+
+	// Logger, when set, receives the events logged by the Middleware, such as rejected requests.
+	// Without a Logger nothing is logged.
+	Logger Logger
+
+	// KeepRawFilenames returns multipart file names exactly as the client sent them. By default
+	// each FileHeader.Filename is reduced to its final path element, treating backslashes as
+	// separators, and files whose name is then empty, "." or ".." are rejected with a 400, so a
+	// name such as "..\..\etc\passwd" can't be used to build a path outside a save directory.
+	KeepRawFilenames bool
+
+	// NormalizeFilenames rewrites multipart file names to lowercase in Unicode NFC normal form, so
+	// the same name sent with composed or decomposed characters by different operating systems,
+	// e.g. "Résumé.pdf", compares equal for deduplication. It doesn't make names safe to use in
+	// paths, which is left to the default sanitizing.
+	NormalizeFilenames bool
+
+	// JSONValidator, when set, is called with the decoded object of every application/json body
+	// before it's flattened into form values, such as the JSON Schema validator set by the
+	// jsonschema subpackage's WithJSONSchema. An error rejects the request with a 400, or with the
+	// status and message of a *ParseError.
+	JSONValidator func(object map[string]interface{}) error
+}
+
+// EmptyResultAction is the action taken when a parsed form contains no values or files
+type EmptyResultAction int
+
+const (
+	// EmptyResultPass returns the empty results without an error
+	EmptyResultPass EmptyResultAction = iota
+	// EmptyResultError rejects the request with a 400
+	EmptyResultError
+)
+
+// MemoryOverflowAction is the action taken when a multipart form does not fit in MaxMemory
+type MemoryOverflowAction int
+
+const (
+	// MemoryOverflowSpillToDisk stores the file parts that don't fit in memory in temporary files
+	MemoryOverflowSpillToDisk MemoryOverflowAction = iota
+	// MemoryOverflowReject rejects the request with a 413
+	MemoryOverflowReject
+)
+
+// EmptyArrayStrategy is how a JSON field containing an empty array is handled
+type EmptyArrayStrategy int
+
+const (
+	// EmptyArrayReject rejects the request with a 400
+	EmptyArrayReject EmptyArrayStrategy = iota
+	// EmptyArrayOmit drops the field from the results, as if it was never sent
+	EmptyArrayOmit
+	// EmptyArrayKeep stores the field in the results with an empty slice of values
+	EmptyArrayKeep
+)
@@ -0,0 +1,107 @@
+package formhandler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig holds the cross-origin rules of CORSHandler
+type CORSConfig struct {
+	// AllowedOrigins are the origins browsers may send forms from, e.g. "https://app.example.com",
+	// or "*" for any origin. Requests from other origins get no CORS headers, so browsers block them.
+	AllowedOrigins []string
+	// AllowedMethods are the methods allowed in cross-origin requests. Defaults to POST.
+	AllowedMethods []string
+	// AllowedContentTypes are the Content-Types the form endpoint accepts. Content-Type is only an
+	// allowed request header when one of them isn't a type browsers send without a preflight
+	// request. Defaults to application/json, application/x-www-form-urlencoded and
+	// multipart/form-data.
+	AllowedContentTypes []string
+	// AllowCredentials allows browsers to send cookies and HTTP authentication with requests. It
+	// can't be combined with a "*" origin, the allowed origins must be listed explicitly.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response, zero leaves it to the browser
+	MaxAge time.Duration
+	// Next handles every request that isn't an OPTIONS request, such as the form's POST handler.
+	// Without a Next they are rejected with a 405.
+	Next http.Handler
+}
+
+// corsSafelistedContentTypes are the Content-Types browsers send cross-origin without a
+// preflight request
+var corsSafelistedContentTypes = []string{headerValFormURLEncoded, headerValFormMultipart, headerValTextPlain}
+
+// CORSHandler answers OPTIONS requests to a form endpoint, including CORS preflight requests,
+// with the Access-Control-Allow-* headers derived from cfg, and passes every other request to
+// cfg.Next with the Access-Control-Allow-Origin header set for allowed origins:
+//
+//	http.Handle("/form", formhandler.CORSHandler(formhandler.CORSConfig{
+//		AllowedOrigins: []string{"https://app.example.com"},
+//		Next:           formhandler.Middleware(formHandler),
+//	}))
+//
+// CORSHandler panics if cfg allows credentials from any origin.
+func CORSHandler(cfg CORSConfig) http.HandlerFunc {
+	anyOrigin := containsString(cfg.AllowedOrigins, "*")
+	if anyOrigin && cfg.AllowCredentials {
+		panic("formhandler: CORSHandler given AllowCredentials with a \"*\" origin")
+	}
+	if len(cfg.AllowedMethods) == 0 {
+		cfg.AllowedMethods = []string{http.MethodPost}
+	}
+	if len(cfg.AllowedContentTypes) == 0 {
+		cfg.AllowedContentTypes = []string{headerValApplicationJSON, headerValFormURLEncoded, headerValFormMultipart}
+	}
+	allow := strings.Join(append([]string{http.MethodOptions}, cfg.AllowedMethods...), ", ")
+
+	var allowedHeaders []string
+	for _, contentType := range cfg.AllowedContentTypes {
+		if !containsString(corsSafelistedContentTypes, contentType) {
+			allowedHeaders = append(allowedHeaders, headerKeyContentType)
+			break
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowedOrigin := origin != "" && (anyOrigin || containsString(cfg.AllowedOrigins, origin))
+		if origin != "" {
+			// responses differ by origin, so caches must not share them
+			w.Header().Add("Vary", "Origin")
+		}
+		if allowedOrigin {
+			if anyOrigin {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if r.Method != http.MethodOptions {
+			if cfg.Next == nil {
+				w.Header().Set("Allow", allow)
+				http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+				return
+			}
+			cfg.Next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Allow", allow)
+		if allowedOrigin && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(cfg.AllowedMethods, ", "))
+			if len(allowedHeaders) > 0 {
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(allowedHeaders, ", "))
+			}
+			if cfg.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
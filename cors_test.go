@@ -0,0 +1,111 @@
+package formhandler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSHandler(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	var corsTests = []struct {
+		testName        string
+		cfg             CORSConfig
+		method          string
+		headers         map[string]string
+		expectedStatus  int
+		expectedHeaders map[string]string
+	}{
+		{
+			"preflight from allowed origin",
+			CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, MaxAge: time.Hour, Next: next},
+			http.MethodOptions,
+			map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST"},
+			http.StatusNoContent,
+			map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.example.com",
+				"Access-Control-Allow-Methods": "POST",
+				"Access-Control-Allow-Headers": "Content-Type",
+				"Access-Control-Max-Age":       "3600",
+				"Allow":                        "OPTIONS, POST",
+				"Vary":                         "Origin",
+			},
+		},
+		{
+			"preflight for safelisted content types only",
+			CORSConfig{AllowedOrigins: []string{"*"}, AllowedContentTypes: []string{"multipart/form-data"}},
+			http.MethodOptions,
+			map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST"},
+			http.StatusNoContent,
+			map[string]string{"Access-Control-Allow-Origin": "*", "Access-Control-Allow-Methods": "POST", "Access-Control-Allow-Headers": ""},
+		},
+		{
+			"preflight from other origin",
+			CORSConfig{AllowedOrigins: []string{"https://app.example.com"}},
+			http.MethodOptions,
+			map[string]string{"Origin": "https://evil.example.com", "Access-Control-Request-Method": "POST"},
+			http.StatusNoContent,
+			map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Methods": ""},
+		},
+		{
+			"options without origin",
+			CORSConfig{AllowedMethods: []string{"POST", "PUT"}},
+			http.MethodOptions,
+			nil,
+			http.StatusNoContent,
+			map[string]string{"Allow": "OPTIONS, POST, PUT", "Access-Control-Allow-Methods": ""},
+		},
+		{
+			"post with credentials",
+			CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true, Next: next},
+			http.MethodPost,
+			map[string]string{"Origin": "https://app.example.com"},
+			http.StatusCreated,
+			map[string]string{"Access-Control-Allow-Origin": "https://app.example.com", "Access-Control-Allow-Credentials": "true"},
+		},
+		{
+			"post with credentials from other origin",
+			CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true, Next: next},
+			http.MethodPost,
+			map[string]string{"Origin": "https://evil.example.com"},
+			http.StatusCreated,
+			map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Credentials": ""},
+		},
+		{
+			"post without next",
+			CORSConfig{AllowedOrigins: []string{"*"}},
+			http.MethodPost,
+			nil,
+			http.StatusMethodNotAllowed,
+			map[string]string{"Allow": "OPTIONS, POST"},
+		},
+	}
+
+	for _, tt := range corsTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/form", nil)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			CORSHandler(tt.cfg)(w, r)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			for key, value := range tt.expectedHeaders {
+				assert.Equal(t, value, w.Header().Get(key), key)
+			}
+		})
+	}
+}
+
+func TestCORSHandler_CredentialsWithAnyOrigin(t *testing.T) {
+	assert.Panics(t, func() {
+		CORSHandler(CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	})
+}
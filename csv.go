@@ -0,0 +1,47 @@
+package formhandler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const headerValTextCSV = "text/csv"
+
+// parseCSV parses a CSV body whose first row holds the field names and every following row
+// holds values, a column with several data rows giving a field with multiple values
+func parseCSV(reader io.Reader) (results map[string][]string, err *ParseError) {
+	records, readErr := csv.NewReader(reader).ReadAll()
+	if readErr != nil {
+		if isRequestTooLarge(readErr) {
+			return nil, &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}
+		}
+		var csvErr *csv.ParseError
+		if errors.As(readErr, &csvErr) && errors.Is(csvErr.Err, csv.ErrFieldCount) {
+			return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Invalid CSV body, row %d has a different number of columns to the header row", csvErr.Line)}
+		}
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Invalid CSV body"}
+	}
+
+	if len(records) < 2 {
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: "CSV body must contain a header row and at least one data row"}
+	}
+
+	header := records[0]
+	for i, field := range header {
+		if field == "" {
+			return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Invalid CSV body, column %d of the header row is empty", i+1)}
+		}
+	}
+
+	results = make(map[string][]string, len(header))
+	for _, record := range records[1:] {
+		for i, value := range record {
+			results[header[i]] = append(results[header[i]], value)
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,88 @@
+package formhandler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFormContent_CSV(t *testing.T) {
+	var formContentTests = []struct {
+		testName             string
+		body                 string
+		expectedValuesOutput map[string][]string
+		expectedStatus       int
+	}{
+		{
+			"single data row",
+			"field1,field2\nvalue1,value2\n",
+			map[string][]string{"field1": {"value1"}, "field2": {"value2"}},
+			0,
+		},
+		{
+			"multiple data rows",
+			"field1,field2\r\nvalue1,value2\r\nvalue3,\"value, 4\"\r\n",
+			map[string][]string{"field1": {"value1", "value3"}, "field2": {"value2", "value, 4"}},
+			0,
+		},
+		{
+			"empty values removed",
+			"field1,field2\nvalue1,\n",
+			map[string][]string{"field1": {"value1"}},
+			0,
+		},
+		{
+			"header row only",
+			"field1,field2\n",
+			nil,
+			http.StatusBadRequest,
+		},
+		{
+			"mismatched column count",
+			"field1,field2\nvalue1,value2,value3\n",
+			nil,
+			http.StatusBadRequest,
+		},
+		{
+			"empty header column",
+			"field1,\nvalue1,value2\n",
+			nil,
+			http.StatusBadRequest,
+		},
+		{
+			"unterminated quote",
+			"field1\n\"value1\n",
+			nil,
+			http.StatusBadRequest,
+		},
+		{
+			"too large",
+			"field1\n" + strings.Repeat("a", megabyte) + "\n",
+			nil,
+			http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range formContentTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			assert.NoError(t, err)
+			r.Header.Set("Content-Type", "text/csv")
+
+			results, _, err := GetFormContent(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+			if tt.expectedStatus != 0 {
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, tt.expectedStatus, pe.Status)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package formhandler
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+const dataURIPrefix = "data:"
+
+// decodeDataURIFields moves the values of the named fields out of results and into files,
+// decoding each value as a data URI, e.g. "data:image/png;base64,iVBORw0KGgo..."
+func decodeDataURIFields(results map[string][]string, files map[string][]*multipart.FileHeader, fields []string) (map[string][]*multipart.FileHeader, *ParseError) {
+	for _, field := range fields {
+		values, ok := results[field]
+		if !ok {
+			continue
+		}
+
+		for _, value := range values {
+			contentType, content, ok := parseDataURI(value)
+			if !ok {
+				return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" must contain a valid data URI`, field)}
+			}
+
+			header, err := newFileHeader(field, field, contentType, content)
+			if err != nil {
+				return nil, &ParseError{Status: http.StatusInternalServerError, Msg: "Form file creation error"}
+			}
+
+			if files == nil {
+				files = make(map[string][]*multipart.FileHeader)
+			}
+			files[field] = append(files[field], header)
+		}
+		delete(results, field)
+	}
+
+	return files, nil
+}
+
+// decodeBase64Fields moves the values of the named fields out of results and into files,
+// decoding each value as standard base64. The content type of each file is sniffed from its
+// content, and the field name is used as its filename.
+func decodeBase64Fields(results map[string][]string, files map[string][]*multipart.FileHeader, fields []string) (map[string][]*multipart.FileHeader, *ParseError) {
+	for _, field := range fields {
+		values, ok := results[field]
+		if !ok {
+			continue
+		}
+
+		for _, value := range values {
+			content, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`JSON field "%s" must contain a base64 encoded file`, field)}
+			}
+
+			header, err := newFileHeader(field, field, http.DetectContentType(content), content)
+			if err != nil {
+				return nil, &ParseError{Status: http.StatusInternalServerError, Msg: "Form file creation error"}
+			}
+
+			if files == nil {
+				files = make(map[string][]*multipart.FileHeader)
+			}
+			files[field] = append(files[field], header)
+		}
+		delete(results, field)
+	}
+
+	return files, nil
+}
+
+// parseDataURI parses a RFC 2397 data URI, returning the media type and decoded content
+func parseDataURI(value string) (contentType string, content []byte, ok bool) {
+	if !strings.HasPrefix(value, dataURIPrefix) {
+		return "", nil, false
+	}
+
+	meta, data, found := strings.Cut(value[len(dataURIPrefix):], ",")
+	if !found {
+		return "", nil, false
+	}
+
+	contentType = meta
+	isBase64 := strings.HasSuffix(meta, ";base64")
+	if isBase64 {
+		contentType = strings.TrimSuffix(meta, ";base64")
+	}
+	if contentType == "" || strings.HasPrefix(contentType, ";") {
+		contentType = "text/plain" + contentType
+	}
+
+	var err error
+	if isBase64 {
+		content, err = base64.StdEncoding.DecodeString(data)
+	} else {
+		var unescaped string
+		unescaped, err = url.PathUnescape(data)
+		content = []byte(unescaped)
+	}
+	if err != nil {
+		return "", nil, false
+	}
+
+	return contentType, content, true
+}
+
+// newFileHeader creates an in-memory *multipart.FileHeader for content that didn't arrive as a
+// multipart file part. FileHeader content can only be set by the multipart package, so the content
+// is written to a single part multipart form which is then read back.
+func newFileHeader(field, filename, contentType string, content []byte) (*multipart.FileHeader, error) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+
+	partHeader := make(textproto.MIMEHeader)
+	partHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(field), escapeQuotes(filename)))
+	partHeader.Set("Content-Type", contentType)
+	part, err := w.CreatePart(partHeader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(content); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	// allow enough memory for the whole content so it is never written to disk
+	form, err := multipart.NewReader(&b, w.Boundary()).ReadForm(int64(b.Len()) + megabyte)
+	if err != nil {
+		return nil, err
+	}
+	return form.File[field][0], nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
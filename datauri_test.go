@@ -0,0 +1,112 @@
+package formhandler
+
+import (
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDataURI(t *testing.T) {
+	var dataURITests = []struct {
+		testName            string
+		value               string
+		expectedContentType string
+		expectedContent     string
+		expectedOk          bool
+	}{
+		{"base64", "data:image/png;base64,aGVsbG8=", "image/png", "hello", true},
+		{"percent encoded", "data:text/csv,a%2Cb", "text/csv", "a,b", true},
+		{"default media type", "data:,hello", "text/plain", "hello", true},
+		{"default media type with parameters", "data:;charset=utf-8,hello", "text/plain;charset=utf-8", "hello", true},
+		{"not a data URI", "hello", "", "", false},
+		{"missing comma", "data:image/png;base64", "", "", false},
+		{"invalid base64", "data:image/png;base64,!!!", "", "", false},
+	}
+
+	for _, tt := range dataURITests {
+		t.Run(tt.testName, func(t *testing.T) {
+			contentType, content, ok := parseDataURI(tt.value)
+			assert.Equal(t, tt.expectedOk, ok)
+			assert.Equal(t, tt.expectedContentType, contentType)
+			assert.Equal(t, tt.expectedContent, string(content))
+		})
+	}
+}
+
+func TestDecodeDataURIFields(t *testing.T) {
+	cfg := Config{MaxFormSize: megabyte, MaxFormWithFilesSize: megabyte, MaxMemory: megabyte, DecodeDataURIFields: []string{"avatar"}}
+
+	r, err := constructURLEncodedForm(url.Values{"name": {"charlie"}, "avatar": {"data:image/png;base64,aGVsbG8="}})
+	assert.NoError(t, err)
+	results, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"name": {"charlie"}}, results)
+	if assert.Len(t, files["avatar"], 1) {
+		assert.Equal(t, "image/png", files["avatar"][0].Header.Get("Content-Type"))
+		f, err := files["avatar"][0].Open()
+		assert.NoError(t, err)
+		content, err := ioutil.ReadAll(f)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(content))
+	}
+
+	r, err = constructMultipartFormParts([]multipartPart{
+		{field: "avatar", value: "data:text/plain,hello"},
+		{field: "upload", fileName: "upload.txt", value: "content"},
+	})
+	assert.NoError(t, err)
+	results, files, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	assert.Len(t, files["avatar"], 1)
+	assert.Len(t, files["upload"], 1)
+
+	r, err = constructURLEncodedForm(url.Values{"avatar": {"not a data uri"}})
+	assert.NoError(t, err)
+	results, files, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.Nil(t, results)
+	assert.Nil(t, files)
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusBadRequest, pe.Status)
+}
+
+func TestJSONFileFields(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.JSONFileFields = []string{"avatar", "attachments"}
+
+	r, err := constructJSONEncodedForm(`{"name": "charlie", "avatar": "` + base64.StdEncoding.EncodeToString([]byte(pngSignature+"image data")) + `", "attachments": ["aGVsbG8=", "d29ybGQ="]}`)
+	assert.NoError(t, err)
+	results, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"name": {"charlie"}}, results)
+	if assert.Len(t, files["avatar"], 1) {
+		assert.Equal(t, "avatar", files["avatar"][0].Filename)
+		assert.Equal(t, "image/png", files["avatar"][0].Header.Get("Content-Type"))
+		assert.Equal(t, int64(len(pngSignature+"image data")), files["avatar"][0].Size)
+	}
+	if assert.Len(t, files["attachments"], 2) {
+		f, err := files["attachments"][1].Open()
+		assert.NoError(t, err)
+		content, err := ioutil.ReadAll(f)
+		assert.NoError(t, err)
+		assert.Equal(t, "world", string(content))
+	}
+
+	r, err = constructJSONEncodedForm(`{"name": "charlie", "avatar": "not base64!"}`)
+	assert.NoError(t, err)
+	results, files, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.Nil(t, results)
+	assert.Nil(t, files)
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusBadRequest, pe.Status)
+		assert.Equal(t, `JSON field "avatar" must contain a base64 encoded file`, pe.Msg)
+	}
+}
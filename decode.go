@@ -0,0 +1,154 @@
+package formhandler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DecodeInto parses the form request the same as GetFormContent and populates the struct
+// pointed to by dst with the results. Struct fields are matched to form fields using the
+// `form:"name"` tag, or the struct field name when untagged, and fields tagged `form:"-"`
+// are skipped. Adding the required option, `form:"name,required"`, rejects requests where
+// the form field is missing or empty with a 400.
+//
+// Supported struct field types are string, bool, integer and float kinds along with slices
+// of them. Scalar struct fields accept a single form value, slices accept any number.
+//
+// The request is parsed with the options given, the same as a Parser constructed by New, or
+// with the defaults of GetFormContent when none are given.
+func DecodeInto(w http.ResponseWriter, r *http.Request, dst interface{}, opts ...Option) error {
+	parser := defaultParser
+	if len(opts) > 0 {
+		parser = New(opts...)
+	}
+	results, _, err := parser.Parse(w, r)
+	if err != nil {
+		return err
+	}
+	return decodeValues(results, dst)
+}
+
+// BindTyped parses the form request with the options given and decodes the results into a new
+// T, which must be a struct type, following the same rules as DecodeInto. A zero T is returned
+// with the error when parsing or decoding fails.
+func BindTyped[T any](w http.ResponseWriter, r *http.Request, opts ...Option) (T, error) {
+	var dst T
+	if err := DecodeInto(w, r, &dst, opts...); err != nil {
+		var zero T
+		return zero, err
+	}
+	return dst, nil
+}
+
+func decodeValues(results map[string][]string, dst interface{}) error {
+	dstValue := reflect.ValueOf(dst)
+	if dstValue.Kind() != reflect.Ptr || dstValue.IsNil() || dstValue.Elem().Kind() != reflect.Struct {
+		return errors.New("formhandler: decode destination must be a non-nil pointer to a struct")
+	}
+	structValue := dstValue.Elem()
+	structType := structValue.Type()
+
+	for i := 0; i < structType.NumField(); i++ {
+		structField := structType.Field(i)
+		if structField.PkgPath != "" {
+			// unexported field
+			continue
+		}
+
+		name, required := parseFormTag(structField)
+		if name == "-" {
+			continue
+		}
+
+		values, ok := results[name]
+		if !ok || len(values) == 0 {
+			if required {
+				return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" is required`, name)}
+			}
+			continue
+		}
+
+		if parseErr := setField(structValue.Field(i), name, values); parseErr != nil {
+			return parseErr
+		}
+	}
+
+	return nil
+}
+
+// parseFormTag returns the form field name and whether the field is required
+// from the struct field's form tag
+func parseFormTag(structField reflect.StructField) (name string, required bool) {
+	tag := structField.Tag.Get("form")
+	name = tag
+	if i := strings.Index(tag, ","); i >= 0 {
+		name = tag[:i]
+		required = tag[i+1:] == "required"
+	}
+	if name == "" {
+		name = structField.Name
+	}
+	return name, required
+}
+
+func setField(field reflect.Value, name string, values []string) *ParseError {
+	if field.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if parseErr := setScalar(slice.Index(i), name, value); parseErr != nil {
+				return parseErr
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	if len(values) > 1 {
+		return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" must only contain a single value`, name)}
+	}
+	return setScalar(field, name, values[0])
+}
+
+func setScalar(field reflect.Value, name, value string) *ParseError {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" must be a boolean`, name)}
+		}
+		field.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" must be an integer`, name)}
+		}
+		field.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" must be a positive integer`, name)}
+		}
+		field.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" must be a number`, name)}
+		}
+		field.SetFloat(f)
+
+	default:
+		return &ParseError{Status: http.StatusInternalServerError, Msg: fmt.Sprintf(`Form field "%s" cannot be decoded into a %s`, name, field.Type())}
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package formhandler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type decodeTestForm struct {
+	Name     string   `form:"name,required"`
+	Age      int      `form:"age"`
+	Height   float64  `form:"height"`
+	Member   bool     `form:"member"`
+	Tags     []string `form:"tags"`
+	Scores   []uint8  `form:"scores"`
+	Ignored  string   `form:"-"`
+	Nickname string
+}
+
+func TestDecodeInto(t *testing.T) {
+	var decodeTests = []struct {
+		testName       string
+		values         url.Values
+		expected       decodeTestForm
+		expectedStatus int
+	}{
+		{
+			"all fields",
+			url.Values{
+				"name":     {"charlie"},
+				"age":      {"30"},
+				"height":   {"1.8"},
+				"member":   {"true"},
+				"tags":     {"a", "b"},
+				"scores":   {"1", "255"},
+				"Nickname": {"chaz"},
+				"-":        {"ignored"},
+			},
+			decodeTestForm{Name: "charlie", Age: 30, Height: 1.8, Member: true, Tags: []string{"a", "b"}, Scores: []uint8{1, 255}, Nickname: "chaz"},
+			0,
+		},
+		{
+			"only required field",
+			url.Values{"name": {"charlie"}},
+			decodeTestForm{Name: "charlie"},
+			0,
+		},
+		{
+			"missing required field",
+			url.Values{"age": {"30"}},
+			decodeTestForm{},
+			http.StatusBadRequest,
+		},
+		{
+			"empty required field",
+			url.Values{"name": {""}},
+			decodeTestForm{},
+			http.StatusBadRequest,
+		},
+		{
+			"invalid int",
+			url.Values{"name": {"charlie"}, "age": {"thirty"}},
+			decodeTestForm{},
+			http.StatusBadRequest,
+		},
+		{
+			"invalid bool",
+			url.Values{"name": {"charlie"}, "member": {"maybe"}},
+			decodeTestForm{},
+			http.StatusBadRequest,
+		},
+		{
+			"invalid float",
+			url.Values{"name": {"charlie"}, "height": {"tall"}},
+			decodeTestForm{},
+			http.StatusBadRequest,
+		},
+		{
+			"out of range slice element",
+			url.Values{"name": {"charlie"}, "scores": {"256"}},
+			decodeTestForm{},
+			http.StatusBadRequest,
+		},
+		{
+			"multiple values for a scalar",
+			url.Values{"name": {"charlie", "charles"}},
+			decodeTestForm{},
+			http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range decodeTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructURLEncodedForm(tt.values)
+			assert.NoError(t, err)
+
+			var dst decodeTestForm
+			err = DecodeInto(httptest.NewRecorder(), r, &dst)
+			if tt.expectedStatus == 0 {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, dst)
+			} else {
+				var pe *ParseError
+				assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+				assert.Equal(t, tt.expectedStatus, pe.Status)
+			}
+		})
+	}
+}
+
+func TestDecodeInto_InvalidDestination(t *testing.T) {
+	r, err := constructJSONEncodedForm(`{"name": "charlie"}`)
+	assert.NoError(t, err)
+
+	var dst decodeTestForm
+	err = DecodeInto(httptest.NewRecorder(), r, dst)
+	assert.Error(t, err)
+	var pe *ParseError
+	assert.False(t, errors.As(err, &pe), "invalid destinations are not a ParseError")
+}
+
+func TestBindTyped(t *testing.T) {
+	r, err := constructJSONEncodedForm(`{"name": "charlie", "age": "30", "tags": ["a", "b"]}`)
+	assert.NoError(t, err)
+
+	form, err := BindTyped[decodeTestForm](httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, decodeTestForm{Name: "charlie", Age: 30, Tags: []string{"a", "b"}}, form)
+
+	r, err = constructJSONEncodedForm(`{"name": "charlie", "age": "thirty"}`)
+	assert.NoError(t, err)
+
+	form, err = BindTyped[decodeTestForm](httptest.NewRecorder(), r)
+	assert.Equal(t, decodeTestForm{}, form)
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusBadRequest, pe.Status)
+
+	// options are applied to the parse
+	r, err = constructJSONEncodedForm(`{"name": "charlie"}`)
+	assert.NoError(t, err)
+
+	form, err = BindTyped[decodeTestForm](httptest.NewRecorder(), r, WithMaxFormSize(8))
+	assert.Equal(t, decodeTestForm{}, form)
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, pe.Status)
+}
@@ -0,0 +1,51 @@
+package formhandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DecodeJSON decodes an application/json request body of at most maxSize bytes straight into
+// a new T, for strongly typed JSON endpoints that don't need the form results. The body must
+// contain a single JSON value, and fields missing from T or values of the wrong type are
+// rejected with a 400 naming the field. A zero T is returned with the error when decoding fails.
+//
+// The error is a *ParseError, so a nil result should be checked before storing it in an
+// error interface.
+func DecodeJSON[T any](w http.ResponseWriter, r *http.Request, maxSize int64) (T, *ParseError) {
+	var zero T
+	if contentType := getContentType(r.Header); contentType != headerValApplicationJSON {
+		if contentType == "" {
+			return zero, missingContentTypeError()
+		}
+		return zero, unsupportedContentTypeError(contentType)
+	}
+	if r.Body == nil {
+		return zero, &ParseError{Status: http.StatusBadRequest, Msg: "Request body must not be empty"}
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSize))
+	dec.DisallowUnknownFields()
+
+	var dst T
+	if decodeErr := dec.Decode(&dst); decodeErr != nil {
+		switch {
+		// encoding/json has no error type for unknown fields, only the message identifies them
+		case strings.HasPrefix(decodeErr.Error(), "json: unknown field "):
+			field := strings.TrimPrefix(decodeErr.Error(), "json: unknown field ")
+			return zero, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Request body contains unknown field %s", field)}
+
+		default:
+			return zero, jsonDecodeError(decodeErr)
+		}
+	}
+
+	if secondDecodeErr := dec.Decode(&struct{}{}); secondDecodeErr != io.EOF {
+		return zero, &ParseError{Status: http.StatusBadRequest, Msg: "Request body must only contain a single JSON object"}
+	}
+
+	return dst, nil
+}
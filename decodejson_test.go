@@ -0,0 +1,66 @@
+package formhandler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	type signup struct {
+		Name    string   `json:"name"`
+		Age     int      `json:"age"`
+		Topics  []string `json:"topics"`
+		Address struct {
+			City string `json:"city"`
+		} `json:"address"`
+	}
+
+	var decodeJSONTests = []struct {
+		testName       string
+		body           string
+		expectedStatus int
+		expectedMsg    string
+	}{
+		{"valid", `{"name":"charlie","age":30,"topics":["go"],"address":{"city":"London"}}`, 0, ""},
+		{"wrong type", `{"name":"charlie","age":"thirty"}`, http.StatusBadRequest, `Request body contains an invalid value for field "age" (at position 32)`},
+		{"wrong nested type", `{"address":{"city":1}}`, http.StatusBadRequest, `Request body contains an invalid value for field "address.city" (at position 20)`},
+		{"not an object", `["charlie"]`, http.StatusBadRequest, `Request body contains an invalid JSON array (at position 1)`},
+		{"unknown field", `{"name":"charlie","admin":true}`, http.StatusBadRequest, `Request body contains unknown field "admin"`},
+		{"multiple objects", `{"name":"a"}{"name":"b"}`, http.StatusBadRequest, "Request body must only contain a single JSON object"},
+		{"malformed", `{"name":`, http.StatusBadRequest, "Request body contains badly-formed JSON"},
+		{"empty", ``, http.StatusBadRequest, "Request body must not be empty"},
+		{"too large", `{"name":"` + strings.Repeat("a", 100) + `"}`, http.StatusRequestEntityTooLarge, "Request body too large"},
+	}
+
+	for _, tt := range decodeJSONTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructJSONEncodedForm(tt.body)
+			assert.NoError(t, err)
+
+			dst, pe := DecodeJSON[signup](httptest.NewRecorder(), r, 100)
+			if tt.expectedStatus == 0 {
+				assert.Nil(t, pe)
+				assert.Equal(t, "charlie", dst.Name)
+				assert.Equal(t, 30, dst.Age)
+				assert.Equal(t, []string{"go"}, dst.Topics)
+				assert.Equal(t, "London", dst.Address.City)
+			} else if assert.NotNil(t, pe) {
+				assert.Equal(t, signup{}, dst)
+				assert.Equal(t, tt.expectedStatus, pe.Status)
+				assert.Equal(t, tt.expectedMsg, pe.Msg)
+			}
+		})
+	}
+
+	r, err := constructURLEncodedForm(url.Values{"name": {"charlie"}})
+	assert.NoError(t, err)
+	_, pe := DecodeJSON[signup](httptest.NewRecorder(), r, 100)
+	if assert.NotNil(t, pe) {
+		assert.Equal(t, http.StatusUnsupportedMediaType, pe.Status)
+	}
+}
@@ -0,0 +1,72 @@
+package formhandler
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const headerKeyContentEncoding = "Content-Encoding"
+
+// decompressingReader decompresses a request body, recording any error from the compressed
+// stream so corrupt data can be reported as such rather than as a parsing error
+type decompressingReader struct {
+	io.ReadCloser
+	body io.Closer
+	err  error
+}
+
+func (dr *decompressingReader) Read(p []byte) (int, error) {
+	n, err := dr.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		dr.err = err
+	}
+	return n, err
+}
+
+func (dr *decompressingReader) Close() error {
+	dr.ReadCloser.Close()
+	return dr.body.Close()
+}
+
+// corruptError returns a 400 if the compressed stream was corrupt
+func (dr *decompressingReader) corruptError() *ParseError {
+	if dr == nil || dr.err == nil {
+		return nil
+	}
+	return &ParseError{Status: http.StatusBadRequest, Msg: "Request body contains corrupt compressed data"}
+}
+
+// decompressBody replaces the body of a gzip or deflate Content-Encoded request with a reader
+// decompressing it, returning nil when the body isn't compressed. Other encodings are rejected
+// with a 415.
+func decompressBody(r *http.Request) (*decompressingReader, *ParseError) {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get(headerKeyContentEncoding)))
+
+	var decompressor io.ReadCloser
+	var err error
+	switch encoding {
+	case "", "identity":
+		return nil, nil
+	case "gzip", "x-gzip":
+		decompressor, err = gzip.NewReader(r.Body)
+	case "deflate":
+		decompressor, err = zlib.NewReader(r.Body)
+	default:
+		return nil, &ParseError{Status: http.StatusUnsupportedMediaType, Msg: fmt.Sprintf("Content-Encoding header %s is unsupported", encoding)}
+	}
+
+	if err != nil {
+		if isRequestTooLarge(err) {
+			return nil, &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}
+		}
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Request body contains corrupt compressed data"}
+	}
+
+	dr := &decompressingReader{ReadCloser: decompressor, body: r.Body}
+	r.Body = dr
+	return dr, nil
+}
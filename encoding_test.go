@@ -0,0 +1,107 @@
+package formhandler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func compressBody(t *testing.T, r *http.Request, encoding string) *http.Request {
+	body, err := ioutil.ReadAll(r.Body)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	if encoding == "deflate" {
+		w = zlib.NewWriter(&buf)
+	} else {
+		w = gzip.NewWriter(&buf)
+	}
+	_, err = w.Write(body)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	r.Body = ioutil.NopCloser(&buf)
+	r.ContentLength = int64(buf.Len())
+	r.Header.Set("Content-Encoding", encoding)
+	return r
+}
+
+func TestGetFormContent_ContentEncoding(t *testing.T) {
+	r, err := constructJSONEncodedForm(`{"field1":"value1"}`)
+	assert.NoError(t, err)
+	results, _, err := GetFormContent(httptest.NewRecorder(), compressBody(t, r, "gzip"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}}, results)
+
+	r, err = constructURLEncodedForm(url.Values{"field1": {"value1"}})
+	assert.NoError(t, err)
+	results, _, err = GetFormContent(httptest.NewRecorder(), compressBody(t, r, "deflate"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}}, results)
+
+	r, err = constructMultipartFormParts([]multipartPart{
+		{field: "field1", value: "value1"},
+		{field: "file1", fileName: "one.txt", value: "one"},
+	})
+	assert.NoError(t, err)
+	results, files, err := GetFormContent(httptest.NewRecorder(), compressBody(t, r, "gzip"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}}, results)
+	assert.Len(t, files["file1"], 1)
+}
+
+func TestGetFormContent_ContentEncodingErrors(t *testing.T) {
+	gzipped := func(t *testing.T, body string) []byte {
+		r, err := constructJSONEncodedForm(body)
+		assert.NoError(t, err)
+		compressed, err := ioutil.ReadAll(compressBody(t, r, "gzip").Body)
+		assert.NoError(t, err)
+		return compressed
+	}
+
+	valid := gzipped(t, `{"field1":"value1"}`)
+	corrupt := append([]byte{}, valid...)
+	corrupt[len(corrupt)-6] ^= 0xff
+	// two megabytes of repeated bytes compress to a few kilobytes
+	bomb := gzipped(t, `{"field1":"`+strings.Repeat("a", 2*megabyte)+`"}`)
+
+	var encodingErrorTests = []struct {
+		testName       string
+		encoding       string
+		body           []byte
+		expectedStatus int
+	}{
+		{"corrupt checksum", "gzip", corrupt, http.StatusBadRequest},
+		{"truncated", "gzip", valid[:len(valid)-10], http.StatusBadRequest},
+		{"not compressed", "gzip", []byte(`{"field1":"value1"}`), http.StatusBadRequest},
+		{"decompressed size limited", "gzip", bomb, http.StatusRequestEntityTooLarge},
+		{"unsupported encoding", "br", valid, http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range encodingErrorTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			assert.Less(t, len(tt.body), megabyte)
+			r, err := constructJSONEncodedForm("")
+			assert.NoError(t, err)
+			r.Body = ioutil.NopCloser(bytes.NewReader(tt.body))
+			r.Header.Set("Content-Encoding", tt.encoding)
+
+			_, _, err = GetFormContent(httptest.NewRecorder(), r)
+			var pe *ParseError
+			if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+				assert.Equal(t, tt.expectedStatus, pe.Status)
+			}
+		})
+	}
+}
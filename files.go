@@ -0,0 +1,308 @@
+package formhandler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// sanitizeFilenames rewrites the filename of every file to its final path element, rejecting
+// files left without a usable name
+func sanitizeFilenames(files map[string][]*multipart.FileHeader) *ParseError {
+	for field, headers := range files {
+		for i, header := range headers {
+			name, ok := baseFilename(header.Filename)
+			if !ok {
+				return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`File of field "%s" has invalid filename "%s"`, field, header.Filename)}
+			}
+			headers[i] = renamedFileHeader(header, name)
+		}
+	}
+	return nil
+}
+
+// renamedFileHeader returns a copy of header with its filename set to name. The headers are
+// shared with r.MultipartForm, which keeps the names as sent so the request can be parsed again.
+func renamedFileHeader(header *multipart.FileHeader, name string) *multipart.FileHeader {
+	if header.Filename == name {
+		return header
+	}
+	renamed := *header
+	renamed.Filename = name
+	return &renamed
+}
+
+// sniffLen is the number of bytes http.DetectContentType considers
+const sniffLen = 512
+
+// normalizeFilenames rewrites the filename of every file to its lowercase NFC normal form, so
+// names typed the same but encoded with composed or decomposed characters are equal
+func normalizeFilenames(files map[string][]*multipart.FileHeader) {
+	for _, headers := range files {
+		for i, header := range headers {
+			headers[i] = renamedFileHeader(header, strings.ToLower(norm.NFC.String(header.Filename)))
+		}
+	}
+}
+
+// fileContentTypeCheck checks the content type of a file, sniffed from its content rather
+// than trusting the part's Content-Type header, is in the allowed list
+func fileContentTypeCheck(allowed []string) fileCheck {
+	return func(field string, header *multipart.FileHeader) *ParseError {
+		contentType, err := sniffContentType(header)
+		if err != nil {
+			return &ParseError{Status: http.StatusInternalServerError, Msg: "Form file read error"}
+		}
+
+		if !containsString(allowed, contentType) {
+			return &ParseError{Status: http.StatusUnsupportedMediaType, Msg: fmt.Sprintf(`File "%s" of field "%s" has unsupported content type %s`, header.Filename, field, contentType)}
+		}
+		return nil
+	}
+}
+
+// emptyFileCheck rejects files with no content, such as those sent by some clients for a file
+// input left empty
+func emptyFileCheck(field string, header *multipart.FileHeader) *ParseError {
+	if header.Size == 0 {
+		return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`File "%s" of field "%s" is empty`, header.Filename, field)}
+	}
+	return nil
+}
+
+// fileExtensionCheck checks the lowercased extension of a file's name is in allowed, rejecting
+// files without an extension
+func fileExtensionCheck(allowed []string) fileCheck {
+	return func(field string, header *multipart.FileHeader) *ParseError {
+		ext := strings.ToLower(filepath.Ext(header.Filename))
+		if ext == "" || !containsString(allowed, ext) {
+			return &ParseError{Status: http.StatusUnsupportedMediaType, Msg: fmt.Sprintf(`File "%s" of field "%s" has an unsupported extension`, header.Filename, field)}
+		}
+		return nil
+	}
+}
+
+// sniffContentType detects the media type of the file from its first 512 bytes, without
+// any parameters such as charset. The file is opened separately from any later reads, so
+// callers opening the file afterwards still read from the start.
+func sniffContentType(header *multipart.FileHeader) (string, error) {
+	f, err := header.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+
+	contentType := http.DetectContentType(buf[:n])
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return contentType, nil
+}
+
+// fileSignatureCheck checks a file whose extension is in signatures starts with the magic bytes
+// of that format
+func fileSignatureCheck(signatures map[string][]byte) fileCheck {
+	return func(field string, header *multipart.FileHeader) *ParseError {
+		signature, ok := signatures[strings.ToLower(filepath.Ext(header.Filename))]
+		if !ok {
+			return nil
+		}
+
+		matches, err := hasPrefix(header, signature)
+		if err != nil {
+			return &ParseError{Status: http.StatusInternalServerError, Msg: "Form file read error"}
+		}
+		if !matches {
+			return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`File "%s" of field "%s" content does not match its extension`, header.Filename, field)}
+		}
+		return nil
+	}
+}
+
+// hasPrefix returns if the file content starts with prefix
+func hasPrefix(header *multipart.FileHeader, prefix []byte) (bool, error) {
+	f, err := header.Open()
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, len(prefix))
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false, err
+	}
+	return n == len(prefix) && bytes.Equal(buf, prefix), nil
+}
+
+// fileCheck checks a single uploaded file, returning the error rejecting it
+type fileCheck func(field string, header *multipart.FileHeader) *ParseError
+
+// FileRejection describes a file removed from a form parsed with RejectFilesIndividually
+type FileRejection struct {
+	Field    string
+	Filename string
+	// Status and Reason are the status and message the whole request would have been rejected
+	// with, if RejectFilesIndividually wasn't set
+	Status int
+	Reason string
+}
+
+// fileChecks returns the checks every uploaded file must pass, in the order they're made
+func (cfg Config) fileChecks() []fileCheck {
+	var checks []fileCheck
+	if cfg.RejectEmptyFiles {
+		checks = append(checks, emptyFileCheck)
+	}
+	if len(cfg.AllowedFileExtensions) > 0 {
+		checks = append(checks, fileExtensionCheck(cfg.AllowedFileExtensions))
+	}
+	if len(cfg.AllowedFileContentTypes) > 0 {
+		checks = append(checks, fileContentTypeCheck(cfg.AllowedFileContentTypes))
+	}
+	if len(cfg.FileSignatureChecks) > 0 {
+		checks = append(checks, fileSignatureCheck(cfg.FileSignatureChecks))
+	}
+	if cfg.ScanFile != nil {
+		// last, as scanning is usually the slowest check
+		checks = append(checks, fileScanCheck(cfg.ScanFile))
+	}
+	return checks
+}
+
+// fileScanCheck passes each file, opened for reading, to scan, rejecting files scan returns an
+// error for with a 422. The file is opened separately from any later reads, so callers opening
+// the file afterwards still read from the start.
+func fileScanCheck(scan func(field string, f multipart.File, header *multipart.FileHeader) error) fileCheck {
+	return func(field string, header *multipart.FileHeader) *ParseError {
+		f, err := header.Open()
+		if err != nil {
+			return &ParseError{Status: http.StatusInternalServerError, Msg: "Uploaded file could not be read", Err: err}
+		}
+		defer f.Close()
+
+		if err := scan(field, f, header); err != nil {
+			return &ParseError{Status: http.StatusUnprocessableEntity, Msg: fmt.Sprintf(`File "%s" of field "%s" was rejected by the file scan`, header.Filename, field), Err: err}
+		}
+		return nil
+	}
+}
+
+// checkFile returns the error of the first check the file fails
+func checkFile(field string, header *multipart.FileHeader, checks []fileCheck) *ParseError {
+	for _, check := range checks {
+		if parseErr := check(field, header); parseErr != nil {
+			return parseErr
+		}
+	}
+	return nil
+}
+
+// validateFiles rejects the form when any file fails a check, naming the first failing file in
+// alphabetical order of field so the error is stable
+func validateFiles(files map[string][]*multipart.FileHeader, checks []fileCheck) *ParseError {
+	for _, field := range sortedFields(files) {
+		for _, header := range files[field] {
+			if parseErr := checkFile(field, header, checks); parseErr != nil {
+				return parseErr
+			}
+		}
+	}
+	return nil
+}
+
+// removeRejectedFiles removes the files failing a check from files and the recorded file order,
+// returning why each was rejected in alphabetical order of field
+func removeRejectedFiles(files map[string][]*multipart.FileHeader, order []string, checks []fileCheck) (map[string][]*multipart.FileHeader, []string, []FileRejection) {
+	var rejections []FileRejection
+	rejectedIndexes := make(map[string]map[int]bool)
+	for _, field := range sortedFields(files) {
+		var kept []*multipart.FileHeader
+		for i, header := range files[field] {
+			parseErr := checkFile(field, header, checks)
+			if parseErr == nil {
+				kept = append(kept, header)
+				continue
+			}
+
+			rejections = append(rejections, FileRejection{Field: field, Filename: header.Filename, Status: parseErr.Status, Reason: parseErr.Msg})
+			if rejectedIndexes[field] == nil {
+				rejectedIndexes[field] = make(map[int]bool)
+			}
+			rejectedIndexes[field][i] = true
+		}
+
+		if len(kept) > 0 {
+			files[field] = kept
+		} else {
+			delete(files, field)
+		}
+	}
+
+	if order != nil && len(rejections) > 0 {
+		keptOrder := make([]string, 0, len(order))
+		seen := make(map[string]int)
+		for _, field := range order {
+			if !rejectedIndexes[field][seen[field]] {
+				keptOrder = append(keptOrder, field)
+			}
+			seen[field]++
+		}
+		order = keptOrder
+	}
+
+	return files, order, rejections
+}
+
+// FileHash is the SHA-256 checksum of an uploaded file, see Config.HashFiles
+type FileHash struct {
+	Filename string
+	// SHA256 is the hex encoded SHA-256 hash of the file's content
+	SHA256 string
+}
+
+// hashFiles hashes every file in a single read of its content, the files can still be opened
+// again afterwards as each FileHeader.Open starts from the beginning of the file
+func hashFiles(files map[string][]*multipart.FileHeader) (map[string][]FileHash, *ParseError) {
+	hashes := make(map[string][]FileHash, len(files))
+	for field, headers := range files {
+		fieldHashes := make([]FileHash, 0, len(headers))
+		for _, header := range headers {
+			sum, err := hashFile(header)
+			if err != nil {
+				return nil, &ParseError{Status: http.StatusInternalServerError, Msg: "Uploaded file could not be read", Err: err}
+			}
+			fieldHashes = append(fieldHashes, FileHash{Filename: header.Filename, SHA256: sum})
+		}
+		hashes[field] = fieldHashes
+	}
+	return hashes, nil
+}
+
+func hashFile(header *multipart.FileHeader) (string, error) {
+	f, err := header.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
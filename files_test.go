@@ -0,0 +1,381 @@
+package formhandler
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+func TestAllowedFileContentTypes(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.AllowedFileContentTypes = []string{"image/png", "image/jpeg"}
+
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "image", fileName: "image.png", value: pngSignature + "image data"},
+	})
+	assert.NoError(t, err)
+	_, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	if assert.Len(t, files["image"], 1) {
+		// the file can still be read from the start after sniffing
+		f, err := files["image"][0].Open()
+		assert.NoError(t, err)
+		content, err := ioutil.ReadAll(f)
+		assert.NoError(t, err)
+		assert.Equal(t, pngSignature+"image data", string(content))
+	}
+
+	// a text file claiming to be a png is rejected
+	r, err = constructMultipartFormParts([]multipartPart{
+		{field: "image", fileName: "image.png", value: "just some text"},
+	})
+	assert.NoError(t, err)
+	_, files, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.Nil(t, files)
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusUnsupportedMediaType, pe.Status)
+}
+
+func TestAllowedFileExtensions(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.AllowedFileExtensions = []string{".pdf", ".docx"}
+
+	var extensionTests = []struct {
+		testName      string
+		fileName      string
+		expectedError bool
+	}{
+		{"allowed extension", "document.pdf", false},
+		{"allowed uppercase extension", "document.DOCX", false},
+		{"disallowed extension", "script.exe", true},
+		{"no extension", "document", true},
+		{"allowed extension not last", "document.pdf.exe", true},
+	}
+
+	for _, tt := range extensionTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructMultipartFormParts([]multipartPart{{field: "file1", fileName: tt.fileName, value: "content"}})
+			assert.NoError(t, err)
+
+			_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+			if tt.expectedError {
+				var pe *ParseError
+				assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+				assert.Equal(t, http.StatusUnsupportedMediaType, pe.Status)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	// both checks apply when combined with content sniffing
+	cfg.AllowedFileContentTypes = []string{"application/pdf"}
+	r, err := constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "document.pdf", value: "just some text"}})
+	assert.NoError(t, err)
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusUnsupportedMediaType, pe.Status)
+}
+
+func TestFileSignatureChecks(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.FileSignatureChecks = map[string][]byte{
+		".pdf": []byte("%PDF"),
+		".png": []byte(pngSignature),
+	}
+
+	var signatureTests = []struct {
+		testName      string
+		fileName      string
+		content       string
+		expectedError bool
+	}{
+		{"valid pdf", "document.pdf", "%PDF-1.7 content", false},
+		{"valid png with uppercase extension", "image.PNG", pngSignature + "image data", false},
+		{"unchecked extension", "notes.txt", "anything", false},
+		{"invalid pdf", "document.pdf", "not a pdf", true},
+		{"file shorter than signature", "image.png", "\x89P", true},
+	}
+
+	for _, tt := range signatureTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructMultipartFormParts([]multipartPart{{field: "file1", fileName: tt.fileName, value: tt.content}})
+			assert.NoError(t, err)
+
+			_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+			if tt.expectedError {
+				var pe *ParseError
+				assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+				assert.Equal(t, http.StatusBadRequest, pe.Status)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNormalizeFilenames(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.NormalizeFilenames = true
+
+	composed := "R\u00e9sum\u00e9.PDF"
+	decomposed := "Re\u0301sume\u0301.PDF"
+	assert.NotEqual(t, composed, decomposed)
+
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "file1", fileName: composed, value: "one"},
+		{field: "file1", fileName: decomposed, value: "two"},
+	})
+	assert.NoError(t, err)
+	_, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	if assert.Len(t, files["file1"], 2) {
+		assert.Equal(t, "r\u00e9sum\u00e9.pdf", files["file1"][0].Filename)
+		assert.Equal(t, files["file1"][0].Filename, files["file1"][1].Filename)
+	}
+}
+
+func TestRejectFilesIndividually(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.AllowedFileExtensions = []string{".png", ".pdf"}
+	cfg.FileSignatureChecks = map[string][]byte{".png": []byte(pngSignature)}
+	cfg.RejectFilesIndividually = true
+	cfg.RecordFileOrder = true
+
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "name", value: "charlie"},
+		{field: "images", fileName: "one.png", value: pngSignature + "one"},
+		{field: "images", fileName: "two.png", value: "not a png"},
+		{field: "images", fileName: "three.png", value: pngSignature + "three"},
+		{field: "script", fileName: "run.exe", value: "binary"},
+		{field: "document", fileName: "document.pdf", value: "%PDF"},
+	})
+	assert.NoError(t, err)
+	form, err := cfg.GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	defer form.Cleanup()
+
+	assert.Equal(t, map[string][]string{"name": {"charlie"}}, form.Values)
+	assert.Equal(t, []FileRejection{
+		{Field: "images", Filename: "two.png", Status: http.StatusBadRequest, Reason: `File "two.png" of field "images" content does not match its extension`},
+		{Field: "script", Filename: "run.exe", Status: http.StatusUnsupportedMediaType, Reason: `File "run.exe" of field "script" has an unsupported extension`},
+	}, form.RejectedFiles)
+
+	ordered := form.OrderedFiles()
+	filenames := make([]string, 0, len(ordered))
+	for _, file := range ordered {
+		filenames = append(filenames, file.Header.Filename)
+	}
+	assert.Equal(t, []string{"one.png", "three.png", "document.pdf"}, filenames)
+	assert.NotContains(t, form.Files, "script")
+
+	// fail fast by default
+	cfg.RejectFilesIndividually = false
+	r, err = constructMultipartFormParts([]multipartPart{
+		{field: "images", fileName: "one.png", value: pngSignature + "one"},
+		{field: "script", fileName: "run.exe", value: "binary"},
+	})
+	assert.NoError(t, err)
+	_, err = cfg.GetForm(httptest.NewRecorder(), r)
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusUnsupportedMediaType, pe.Status)
+	}
+}
+
+func TestSanitizeFilenames(t *testing.T) {
+	var filenameTests = []struct {
+		testName         string
+		fileName         string
+		expectedFileName string
+		expectedError    bool
+	}{
+		{"plain name", "photo.png", "photo.png", false},
+		{"unix traversal", "../../etc/passwd", "passwd", false},
+		{"windows traversal", `..\..\windows\win.ini`, "win.ini", false},
+		{"dot dot", "..", "", true},
+		{"trailing separator", `uploads\..\`, "", true},
+	}
+
+	for _, tt := range filenameTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructMultipartFormParts([]multipartPart{{field: "file1", fileName: tt.fileName, value: "content"}})
+			assert.NoError(t, err)
+
+			_, files, err := GetFormContent(httptest.NewRecorder(), r)
+			if tt.expectedError {
+				var pe *ParseError
+				assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+				assert.Equal(t, http.StatusBadRequest, pe.Status)
+			} else if assert.NoError(t, err) && assert.Len(t, files["file1"], 1) {
+				assert.Equal(t, tt.expectedFileName, files["file1"][0].Filename)
+			}
+		})
+	}
+
+	// the raw name is kept when sanitizing is turned off
+	cfg := defaultConfig()
+	cfg.KeepRawFilenames = true
+	r, err := constructMultipartFormParts([]multipartPart{{field: "file1", fileName: `..\..\windows\win.ini`, value: "content"}})
+	assert.NoError(t, err)
+	_, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	if assert.Len(t, files["file1"], 1) {
+		assert.Equal(t, `..\..\windows\win.ini`, files["file1"][0].Filename)
+	}
+
+	// sanitizing doesn't rewrite the names in r.MultipartForm, so a request parsed again
+	// without sanitizing still has the raw name
+	r, err = constructMultipartFormParts([]multipartPart{{field: "file1", fileName: `..\..\windows\Win.ini`, value: "content"}})
+	assert.NoError(t, err)
+	normalizing := defaultConfig()
+	normalizing.NormalizeFilenames = true
+	_, files, err = normalizing.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	if assert.Len(t, files["file1"], 1) {
+		assert.Equal(t, "win.ini", files["file1"][0].Filename)
+	}
+	_, files, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	if assert.Len(t, files["file1"], 1) {
+		assert.Equal(t, `..\..\windows\Win.ini`, files["file1"][0].Filename)
+	}
+}
+
+func TestHashFiles(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.HashFiles = true
+	// the larger file is stored on disk
+	cfg.MaxMemory = 16
+
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "name", value: "charlie"},
+		{field: "files", fileName: "one.txt", value: "one"},
+		{field: "files", fileName: "two.txt", value: "two, with more content than MaxMemory"},
+	})
+	assert.NoError(t, err)
+	form, err := cfg.GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	defer form.Cleanup()
+
+	assert.Equal(t, map[string][]FileHash{"files": {
+		{Filename: "one.txt", SHA256: fmt.Sprintf("%x", sha256.Sum256([]byte("one")))},
+		{Filename: "two.txt", SHA256: fmt.Sprintf("%x", sha256.Sum256([]byte("two, with more content than MaxMemory")))},
+	}}, form.FileHashes)
+
+	// the files can still be saved after hashing
+	dir := t.TempDir()
+	saved, err := SaveFiles(form.Files, dir)
+	assert.NoError(t, err)
+	content, err := ioutil.ReadFile(saved["files"][1])
+	assert.NoError(t, err)
+	assert.Equal(t, "two, with more content than MaxMemory", string(content))
+
+	// no hashes without the option
+	r, err = constructMultipartFormParts([]multipartPart{{field: "files", fileName: "one.txt", value: "one"}})
+	assert.NoError(t, err)
+	form, err = defaultConfig().GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Nil(t, form.FileHashes)
+}
+
+func TestRejectEmptyFiles(t *testing.T) {
+	parts := []multipartPart{
+		{field: "name", value: "charlie"},
+		{field: "documents", fileName: "empty.txt", value: ""},
+		{field: "documents", fileName: "full.txt", value: "content"},
+		{field: "avatar", fileName: "avatar.png", value: ""},
+	}
+
+	// the empty files are returned by default
+	r, err := constructMultipartFormParts(parts)
+	assert.NoError(t, err)
+	_, files, err := defaultConfig().GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Len(t, files["documents"], 2)
+	assert.Len(t, files["avatar"], 1)
+
+	cfg := defaultConfig()
+	cfg.RejectEmptyFiles = true
+	r, err = constructMultipartFormParts(parts)
+	assert.NoError(t, err)
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusBadRequest, pe.Status)
+		assert.Equal(t, `File "avatar.png" of field "avatar" is empty`, pe.Msg)
+	}
+
+	cfg.RejectFilesIndividually = true
+	r, err = constructMultipartFormParts(parts)
+	assert.NoError(t, err)
+	form, err := cfg.GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	defer form.Cleanup()
+	assert.Equal(t, map[string][]string{"name": {"charlie"}}, form.Values)
+	if assert.Len(t, form.Files, 1) && assert.Len(t, form.Files["documents"], 1) {
+		assert.Equal(t, "full.txt", form.Files["documents"][0].Filename)
+	}
+	assert.Equal(t, []FileRejection{
+		{Field: "avatar", Filename: "avatar.png", Status: http.StatusBadRequest, Reason: `File "avatar.png" of field "avatar" is empty`},
+		{Field: "documents", Filename: "empty.txt", Status: http.StatusBadRequest, Reason: `File "empty.txt" of field "documents" is empty`},
+	}, form.RejectedFiles)
+}
+
+func TestScanFile(t *testing.T) {
+	errInfected := errors.New("Eicar-Test-Signature FOUND")
+	var scanned []string
+	cfg := defaultConfig()
+	cfg.ScanFile = func(field string, f multipart.File, header *multipart.FileHeader) error {
+		content, err := ioutil.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		scanned = append(scanned, field+"/"+header.Filename)
+		if strings.Contains(string(content), "EICAR") {
+			return errInfected
+		}
+		return nil
+	}
+
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "name", value: "charlie"},
+		{field: "document", fileName: "clean.txt", value: "clean content"},
+	})
+	assert.NoError(t, err)
+	_, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"document/clean.txt"}, scanned)
+
+	// the scanned file still reads from the start
+	f, err := files["document"][0].Open()
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(f)
+	assert.NoError(t, err)
+	assert.Equal(t, "clean content", string(content))
+
+	r, err = constructMultipartFormParts([]multipartPart{
+		{field: "document", fileName: "clean.txt", value: "clean content"},
+		{field: "document", fileName: "infected.txt", value: "EICAR test file"},
+	})
+	assert.NoError(t, err)
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusUnprocessableEntity, pe.Status)
+		assert.Equal(t, `File "infected.txt" of field "document" was rejected by the file scan`, pe.Msg)
+		assert.True(t, errors.Is(err, errInfected))
+	}
+}
@@ -0,0 +1,168 @@
+package formhandler
+
+import (
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"time"
+)
+
+// Form is the parsed content of a form request
+type Form struct {
+	Values map[string][]string
+	Files  map[string][]*multipart.FileHeader
+
+	// BodySize is the number of bytes read from the request body while parsing it, which
+	// unlike the Content-Length header can't be misreported by the client
+	BodySize int64
+
+	// FileHashes holds the SHA-256 checksum of every file in Files, keyed by field in the same
+	// order, when parsed with Config.HashFiles
+	FileHashes map[string][]FileHash
+
+	// RejectedFiles lists the files removed from Files for failing a check, when parsed with
+	// Config.RejectFilesIndividually
+	RejectedFiles []FileRejection
+
+	// fileOrder holds the field of each multipart file part in body order, see OrderedFiles
+	fileOrder []string
+	// multipartForm is the parsed multipart form holding any temporary files
+	multipartForm *multipart.Form
+	// boundary is the boundary parameter of a multipart/form-data Content-Type header
+	boundary string
+	// dropped holds the sorted names of the unanswered fields removed, for WarnDroppedFields
+	dropped []string
+}
+
+// GetForm operates the same as GetFormContent, returning the results and files as a Form
+func GetForm(w http.ResponseWriter, r *http.Request) (*Form, error) {
+	return defaultParser.ParseForm(w, r)
+}
+
+// Cleanup removes the temporary files multipart files larger than MaxMemory are stored in. An
+// http.Server removes them once the handler returns, but forms parsed outside of one, such as
+// in tests or background jobs, leave them on disk unless Cleanup is called, usually deferred
+// after parsing. The Middleware calls Cleanup once the next handler returns, and files can't
+// be opened after Cleanup.
+func (f *Form) Cleanup() error {
+	if f.multipartForm == nil {
+		return nil
+	}
+	return f.multipartForm.RemoveAll()
+}
+
+// GetTime parses the first value of field using the time layout
+func (f *Form) GetTime(field, layout string) (time.Time, error) {
+	values := f.Values[field]
+	if len(values) == 0 {
+		return time.Time{}, fmt.Errorf(`form field "%s" is not set`, field)
+	}
+	return time.Parse(layout, values[0])
+}
+
+// Summary describes the size of a Form
+type Summary struct {
+	// ValueFieldCount is the number of fields with values
+	ValueFieldCount int
+	// FileFieldCount is the number of fields with files
+	FileFieldCount int
+	// FileCount is the number of files across all file fields
+	FileCount int
+	// TotalBytes is the combined size of every value and file
+	TotalBytes int64
+}
+
+// Summary counts the fields, files and bytes in the form
+func (f *Form) Summary() Summary {
+	summary := Summary{
+		ValueFieldCount: len(f.Values),
+		FileFieldCount:  len(f.Files),
+	}
+
+	for _, values := range f.Values {
+		for _, value := range values {
+			summary.TotalBytes += int64(len(value))
+		}
+	}
+
+	for _, headers := range f.Files {
+		summary.FileCount += len(headers)
+		for _, header := range headers {
+			summary.TotalBytes += header.Size
+		}
+	}
+
+	return summary
+}
+
+// FileInfo describes an uploaded file along with the headers of the multipart part it was sent in
+type FileInfo struct {
+	// Header is the file as it appears in Form.Files
+	Header *multipart.FileHeader
+	// ContentType is the media type the client declared in the part's Content-Type header,
+	// lowercased and without parameters, or empty if the header is missing or invalid. It is
+	// not checked against the file's content, see Config.AllowedFileContentTypes for that.
+	ContentType string
+	// Size is the size of the file in bytes
+	Size int64
+	// PartHeader is the full MIME header of the part, including its Content-Type and
+	// Content-Disposition headers
+	PartHeader textproto.MIMEHeader
+}
+
+// FileInfo returns a FileInfo for every file in the form, keyed by field in the same order as
+// Files, for checking the headers clients sent with each file before opening it
+func (f *Form) FileInfo() map[string][]FileInfo {
+	infos := make(map[string][]FileInfo, len(f.Files))
+	for field, headers := range f.Files {
+		fieldInfos := make([]FileInfo, 0, len(headers))
+		for _, header := range headers {
+			contentType, _, _ := mime.ParseMediaType(header.Header.Get(headerKeyContentType))
+			fieldInfos = append(fieldInfos, FileInfo{
+				Header:      header,
+				ContentType: contentType,
+				Size:        header.Size,
+				PartHeader:  header.Header,
+			})
+		}
+		infos[field] = fieldInfos
+	}
+	return infos
+}
+
+// Boundary returns the boundary of a multipart/form-data form, as declared in the request's
+// Content-Type header, or an empty string for other forms
+func (f *Form) Boundary() string {
+	return f.boundary
+}
+
+// redactedValue replaces the values of sensitive fields in Form.Redacted
+const redactedValue = "[REDACTED]"
+
+// Redacted returns a copy of the form values safe for logging, with the values of the sensitive
+// fields replaced by a single "[REDACTED]" value
+func (f *Form) Redacted(sensitive []string) map[string][]string {
+	redacted := make(map[string][]string, len(f.Values))
+	for field, values := range f.Values {
+		if containsString(sensitive, field) {
+			redacted[field] = []string{redactedValue}
+			continue
+		}
+		redacted[field] = append([]string(nil), values...)
+	}
+	return redacted
+}
+
+// validateDateFields checks every value of the fields in dateFields parses with the field's time layout
+func validateDateFields(results map[string][]string, dateFields map[string]string) *ParseError {
+	for field, layout := range dateFields {
+		for _, value := range results[field] {
+			if _, err := time.Parse(layout, value); err != nil {
+				return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" must be a date matching the layout "%s"`, field, layout)}
+			}
+		}
+	}
+	return nil
+}
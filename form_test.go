@@ -0,0 +1,208 @@
+package formhandler
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetForm(t *testing.T) {
+	r, err := constructURLEncodedForm(url.Values{"field1": {"value1"}})
+	assert.NoError(t, err)
+
+	form, err := GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}}, form.Values)
+	assert.Empty(t, form.Files)
+}
+
+func TestGetForm_BodySize(t *testing.T) {
+	body := `{"field1":"value1"}`
+	r, err := constructJSONEncodedForm(body)
+	assert.NoError(t, err)
+	// the size read is reported, not the size claimed by the client
+	r.ContentLength = 1
+	form, err := GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(body)), form.BodySize)
+
+	// failed requests report the bytes read before the failure
+	cfg := defaultConfig()
+	cfg.MaxFormSize = 16
+	r, err = constructJSONEncodedForm(`{"field1":"` + strings.Repeat("a", 100) + `"}`)
+	assert.NoError(t, err)
+	_, err = cfg.GetForm(httptest.NewRecorder(), r)
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, pe.Status)
+		assert.Equal(t, int64(17), pe.BodySize)
+	}
+
+	r, err = constructJSONEncodedForm(`{"field1":1}`)
+	assert.NoError(t, err)
+	_, err = GetForm(httptest.NewRecorder(), r)
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, int64(12), pe.BodySize)
+	}
+}
+
+func TestFormCleanup(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+	cfg := Config{MaxFormSize: megabyte, MaxFormWithFilesSize: megabyte, MaxMemory: 16}
+
+	r, err := constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "one.txt", value: strings.Repeat("a", 1024)}})
+	assert.NoError(t, err)
+	form, err := cfg.GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+
+	tempFiles, err := ioutil.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Len(t, tempFiles, 1, "the file should be stored on disk")
+
+	assert.NoError(t, form.Cleanup())
+	tempFiles, err = ioutil.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Empty(t, tempFiles, "temp files should be removed")
+
+	// forms without files have nothing to clean up
+	r, err = constructURLEncodedForm(url.Values{"field1": {"value1"}})
+	assert.NoError(t, err)
+	form, err = cfg.GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.NoError(t, form.Cleanup())
+
+	// files of forms rejected after parsing are removed
+	cfg.AllowedFields = []string{"field1"}
+	r, err = constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "one.txt", value: strings.Repeat("a", 1024)}})
+	assert.NoError(t, err)
+	_, err = cfg.GetForm(httptest.NewRecorder(), r)
+	assert.Error(t, err)
+	tempFiles, err = ioutil.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Empty(t, tempFiles, "temp files should be removed")
+}
+
+func TestFormFileInfo(t *testing.T) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	assert.NoError(t, mw.SetBoundary("test-boundary"))
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {`form-data; name="image"; filename="photo.png"`},
+		"Content-Type":        {"Image/PNG; name=photo"},
+		"X-Checksum":          {"abc123"},
+	})
+	assert.NoError(t, err)
+	_, err = part.Write([]byte("image data"))
+	assert.NoError(t, err)
+	part, err = mw.CreateFormFile("image", "untyped.bin")
+	assert.NoError(t, err)
+	_, err = part.Write([]byte("data"))
+	assert.NoError(t, err)
+	assert.NoError(t, mw.Close())
+
+	r, err := http.NewRequest(http.MethodPost, "/", &buf)
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	form, err := GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-boundary", form.Boundary())
+
+	infos := form.FileInfo()
+	if assert.Len(t, infos["image"], 2) {
+		info := infos["image"][0]
+		assert.Equal(t, form.Files["image"][0], info.Header)
+		assert.Equal(t, "image/png", info.ContentType)
+		assert.Equal(t, int64(10), info.Size)
+		assert.Equal(t, "abc123", info.PartHeader.Get("X-Checksum"))
+		assert.Equal(t, `form-data; name="image"; filename="photo.png"`, info.PartHeader.Get("Content-Disposition"))
+
+		assert.Equal(t, "application/octet-stream", infos["image"][1].ContentType)
+	}
+
+	// forms without files have no boundary or file info
+	r, err = constructURLEncodedForm(url.Values{"field1": {"value1"}})
+	assert.NoError(t, err)
+	form, err = GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Empty(t, form.Boundary())
+	assert.Empty(t, form.FileInfo())
+}
+
+func TestDateFields(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.DateFields = map[string]string{"birthday": "2006-01-02"}
+
+	r, err := constructJSONEncodedForm(`{"birthday": "1990-07-21", "name": "charlie"}`)
+	assert.NoError(t, err)
+	form, err := cfg.GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+
+	birthday, err := form.GetTime("birthday", "2006-01-02")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(1990, time.July, 21, 0, 0, 0, 0, time.UTC), birthday)
+
+	_, err = form.GetTime("missing", "2006-01-02")
+	assert.Error(t, err)
+
+	r, err = constructURLEncodedForm(url.Values{"birthday": {"21/07/1990"}})
+	assert.NoError(t, err)
+	form, err = cfg.GetForm(httptest.NewRecorder(), r)
+	assert.Nil(t, form)
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusBadRequest, pe.Status)
+	assert.Contains(t, pe.Msg, `"birthday"`)
+	assert.Contains(t, pe.Msg, `"2006-01-02"`)
+}
+
+func TestFormSummary(t *testing.T) {
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "field1", value: "value1"},
+		{field: "field1", value: "value2"},
+		{field: "field2", value: "value3"},
+		{field: "file1", fileName: "a.txt", value: "aaaa"},
+		{field: "file1", fileName: "b.txt", value: "bb"},
+		{field: "file2", fileName: "c.txt", value: "c"},
+	})
+	assert.NoError(t, err)
+
+	form, err := GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, Summary{
+		ValueFieldCount: 2,
+		FileFieldCount:  2,
+		FileCount:       3,
+		TotalBytes:      18 + 7,
+	}, form.Summary())
+}
+
+func TestFormRedacted(t *testing.T) {
+	form := &Form{Values: map[string][]string{
+		"name":     {"charlie"},
+		"password": {"hunter2"},
+		"ssn":      {"123", "456"},
+	}}
+
+	redacted := form.Redacted([]string{"password", "ssn", "missing"})
+	assert.Equal(t, map[string][]string{
+		"name":     {"charlie"},
+		"password": {"[REDACTED]"},
+		"ssn":      {"[REDACTED]"},
+	}, redacted)
+
+	// the form values are unchanged and not shared with the copy
+	redacted["name"][0] = "changed"
+	assert.Equal(t, []string{"charlie"}, form.Values["name"])
+	assert.Equal(t, []string{"hunter2"}, form.Values["password"])
+}
@@ -1,13 +1,20 @@
 package formhandler
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"mime"
 	"mime/multipart"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -20,9 +27,14 @@ const (
 	megabyte = 1_048_576
 )
 
+// methodsWithoutBody are the methods rejected when Config.AllowedMethods is unset, as their
+// requests aren't expected to carry a form
+var methodsWithoutBody = []string{http.MethodGet, http.MethodHead, http.MethodDelete}
+
 // GetFormContent accepts a request of content type "application/x-www-form-urlencoded",
-// "application/json" or "multipart/form-data", parses the body and returns the form data
-// and files contained in the request
+// "application/json", "application/xml", "text/csv", "multipart/form-data" or one registered
+// with RegisterBodyParser, parses the body and returns the form data and files contained in the
+// request
 func GetFormContent(
 	w http.ResponseWriter,
 	r *http.Request,
@@ -31,43 +43,469 @@ func GetFormContent(
 	files map[string][]*multipart.FileHeader,
 	err error,
 ) {
-	return GetFormContentWithConfig(megabyte, megabyte*10, megabyte*10)(w, r)
+	return defaultParser.Parse(w, r)
+}
+
+// defaultConfig returns the Config used by the package level functions
+func defaultConfig() Config {
+	return Config{
+		MaxFormSize:          megabyte,
+		MaxFormWithFilesSize: megabyte * 10,
+		MaxMemory:            megabyte * 10,
+		MaxPartHeaderSize:    defaultMaxPartHeaderSize,
+	}
+}
+
+// withDefaults returns the Config with each size limit left at zero set to its default
+func (cfg Config) withDefaults() Config {
+	defaults := defaultConfig()
+	if cfg.MaxFormSize == 0 {
+		cfg.MaxFormSize = defaults.MaxFormSize
+	}
+	if cfg.MaxFormWithFilesSize == 0 {
+		cfg.MaxFormWithFilesSize = defaults.MaxFormWithFilesSize
+	}
+	if cfg.MaxMemory == 0 {
+		cfg.MaxMemory = defaults.MaxMemory
+	}
+	if cfg.MaxPartHeaderSize == 0 {
+		cfg.MaxPartHeaderSize = defaults.MaxPartHeaderSize
+	}
+	return cfg
 }
 
 // GetFormContentWithConfig operates the same as GetFormContent but with added config options:
 // - maxFormSize: The maximum size in bytes a form request can be (applies to JSON and URL encoded forms, which cannot have files attached)
 // - maxFormWithFilesSize: The maximum size in bytes a form request with attached files can be (applies to multipart/form-data encoded forms, which can have files attached)
 // - maxMemory: Given a form request body is parsed, maxMemory bytes of its file parts are stored in memory, with the remainder stored on disk in temporary files (applies to multipart/form-data encoded forms, which can have files attached)
+//
+// Zero values use the same defaults as the Config fields.
+//
+// Deprecated: the three int64 parameters are easily transposed, set the named fields of a
+// Config and use its GetFormContent method instead.
 func GetFormContentWithConfig(
 	maxFormSize int64,
 	maxFormWithFilesSize int64,
 	maxMemory int64,
 ) func(w http.ResponseWriter, r *http.Request) (results map[string][]string, files map[string][]*multipart.FileHeader, err error) {
-	return func(w http.ResponseWriter, r *http.Request) (results map[string][]string, files map[string][]*multipart.FileHeader, err error) {
+	return Config{
+		MaxFormSize:          maxFormSize,
+		MaxFormWithFilesSize: maxFormWithFilesSize,
+		MaxMemory:            maxMemory,
+	}.GetFormContent
+}
+
+// GetFormContent operates the same as the package level GetFormContent, using the
+// limits and options set in the Config
+func (cfg Config) GetFormContent(w http.ResponseWriter, r *http.Request) (results map[string][]string, files map[string][]*multipart.FileHeader, err error) {
+	form, err := cfg.GetForm(w, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return form.Values, form.Files, nil
+}
+
+// GetForm operates the same as GetFormContent, returning the results and files as a Form
+func (cfg Config) GetForm(w http.ResponseWriter, r *http.Request) (form *Form, err error) {
+	cfg = cfg.withDefaults()
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	body := &countingReader{ReadCloser: r.Body}
+	r.Body = body
+
+	contentType := cfg.getContentType(r)
+	if cfg.Metrics != nil {
+		start := time.Now()
+		defer func() {
+			cfg.Metrics.ObserveParse(metricsContentType(contentType), body.count(), time.Since(start), err)
+		}()
+	}
+
+	var parseErr *ParseError
+	if cfg.ParseTimeout > 0 {
+		form, parseErr = cfg.parseWithTimeout(w, r, body, contentType)
+	} else {
+		form, parseErr = cfg.parseOrRemoveFiles(w, r, body, contentType)
+	}
+	// return an untyped nil error on success, a nil *ParseError stored in the error
+	// interface would not compare equal to nil
+	if parseErr == nil {
+		parseErr = cfg.appendToBatch(r, form)
+	}
+	if parseErr != nil {
+		parseErr.BodySize = body.count()
+		return nil, parseErr
+	}
+	form.BodySize = body.count()
+	// the warning is only added once nothing can reject the form
+	if cfg.WarnDroppedFields && len(form.dropped) > 0 {
+		w.Header().Add("Warning", fmt.Sprintf(`199 - "Dropped empty form fields: %s"`, strings.Join(form.dropped, ", ")))
+	}
+	return form, nil
+}
+
+// appendToBatch appends the parsed form to the BatchAccumulator when the request carries a
+// batch token, removing its temporary files when it's rejected
+func (cfg Config) appendToBatch(r *http.Request, form *Form) *ParseError {
+	if cfg.BatchAccumulator == nil {
+		return nil
+	}
+	tokenHeader := cfg.BatchTokenHeader
+	if tokenHeader == "" {
+		tokenHeader = defaultBatchTokenHeader
+	}
+	token := r.Header.Get(tokenHeader)
+	if token == "" {
+		return nil
+	}
+
+	err := cfg.BatchAccumulator.Append(token, form)
+	if err == nil {
+		return nil
+	}
+	form.Cleanup()
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr
+	}
+	return &ParseError{Status: http.StatusInternalServerError, Msg: "Form could not be added to the batch", Err: err}
+}
+
+// parseOrRemoveFiles parses the request, removing any temporary files when it fails
+func (cfg Config) parseOrRemoveFiles(w http.ResponseWriter, r *http.Request, body *countingReader, contentType string) (*Form, *ParseError) {
+	form, parseErr := cfg.parse(w, r, body, contentType)
+	if parseErr != nil {
+		// files rejected by a check after parsing would otherwise be left on disk
+		if r.MultipartForm != nil {
+			r.MultipartForm.RemoveAll()
+		}
+		return nil, parseErr
+	}
+	return form, nil
+}
 
-		switch contentType := getContentType(r.Header); contentType {
+func (cfg Config) parse(w http.ResponseWriter, r *http.Request, body *countingReader, contentType string) (*Form, *ParseError) {
+	if parseErr := cfg.validateMethod(r); parseErr != nil {
+		return nil, parseErr
+	}
+
+	if cfg.RequireMultipartForFiles && cfg.checksFiles() && contentType != "" && contentType != headerValFormMultipart {
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Content-Type header %s cannot carry files, uploading files requires a %s request", contentType, headerValFormMultipart)}
+	}
+
+	if allowed, ok := cfg.MethodContentTypes[r.Method]; ok && !containsString(allowed, contentType) {
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Content-Type header %s is not allowed for %s requests", contentType, r.Method)}
+	}
+
+	var results map[string][]string
+	var files map[string][]*multipart.FileHeader
+	var dropped []string
+	var fileOrder []string
+	var parseErr *ParseError
+
+	// a form already parsed from the body, by an earlier call or by the caller, is reused rather
+	// than reading the consumed body again
+	parsed := (contentType == headerValFormURLEncoded && r.PostForm != nil) ||
+		(contentType == headerValFormMultipart && r.MultipartForm != nil)
+
+	if cfg.RequireContentLength && contentType == headerValFormMultipart && !parsed {
+		// checked before any of the body is read
+		if parseErr = validateContentLength(r, cfg.bodyLimit(contentType)); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	if cfg.BufferBody && !parsed {
+		var buffered []byte
+		if buffered, parseErr = bufferBody(w, r, cfg.bodyLimit(contentType)); parseErr != nil {
+			return nil, parseErr
+		}
+		// whether parsing succeeds or not, the body can be read again from the start
+		defer func() {
+			r.Body = ioutil.NopCloser(bytes.NewReader(buffered))
+		}()
+	}
+
+	// the size limits below apply to the decompressed body, so small compressed bodies can't
+	// expand without limit
+	var decompressed *decompressingReader
+	if !parsed {
+		if decompressed, parseErr = decompressBody(r); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	switch contentType {
+
+	case headerValApplicationJSON:
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxFormSize)
+		results, parseErr = cfg.parseApplicationJSON(r.Body)
 
-		case headerValApplicationJSON:
-			r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
-			results, err = parseApplicationJSON(r.Body)
+	case headerValApplicationXML, headerValTextXML:
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxFormSize)
+		results, parseErr = cfg.parseApplicationXML(r.Body)
 
-		case headerValFormURLEncoded:
-			r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
-			results, err = parseFormURLEncoded(r)
+	case headerValTextCSV:
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxFormSize)
+		results, parseErr = parseCSV(r.Body)
 
-		case headerValFormMultipart:
-			r.Body = http.MaxBytesReader(w, r.Body, maxFormWithFilesSize)
-			results, files, err = parseFormMultipart(r, maxMemory)
+	case headerValFormURLEncoded:
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxFormSize)
+		results, parseErr = cfg.parseFormURLEncoded(r)
 
-		case "":
-			err = &ParseError{Status: http.StatusUnsupportedMediaType, Msg: fmt.Sprintf("Content-Type header is required")}
+	case headerValFormMultipart:
+		maxSize := cfg.bodyLimit(contentType)
+		cancellable := &contextReader{ReadCloser: r.Body, ctx: r.Context()}
+		r.Body = http.MaxBytesReader(w, cancellable, maxSize)
+		var headerLimiter *partHeaderLimiter
+		if !parsed {
+			headerLimiter = limitPartHeaders(r, cfg.MaxPartHeaderSize)
+		}
+		var recorder *fileOrderRecorder
+		if cfg.RecordFileOrder && !parsed {
+			recorder = recordFileOrder(r)
+		}
+		results, files, parseErr = parseFormMultipart(r, cfg.MaxMemory)
+		if headerLimiter.exceeded() {
+			parseErr = partHeaderTooLargeError(cfg.MaxPartHeaderSize)
+		}
+		fileOrder = recorder.finish()
+		if cancellable.err != nil {
+			// ReadForm removes its temp files when it fails, any form left is removed too
+			if r.MultipartForm != nil {
+				r.MultipartForm.RemoveAll()
+			}
+			parseErr = &ParseError{Status: http.StatusRequestTimeout, Msg: "Request cancelled before the form was parsed", Err: cancellable.err}
+		}
+		// counted before any file is opened, the temp files are removed when the form is rejected
+		if parseErr == nil && cfg.MaxFiles > 0 {
+			parseErr = validateFileCount(files, cfg.MaxFiles)
+		}
+		if parseErr == nil && len(cfg.JSONMultipartFields) > 0 {
+			parseErr = cfg.mergeJSONMultipartFields(results)
+		}
+
+	case headerValTextPlain:
+		if !cfg.ParsePlainTextForms {
+			parseErr = unsupportedContentTypeError(contentType)
+			break
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxFormSize)
+		results, parseErr = parseTextPlainForm(r)
+
+	case "":
+		parseErr = missingContentTypeError()
+
+	default:
+		parse, ok := lookupBodyParser(contentType)
+		if !ok {
+			parseErr = unsupportedContentTypeError(contentType)
+			break
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxFormSize)
+		results, parseErr = cfg.parseRegistered(parse, r, r.Body)
+	}
+
+	// a corrupt compressed stream fails parsing with a misleading error, or ends it early
+	if corruptErr := decompressed.corruptError(); corruptErr != nil {
+		return nil, corruptErr
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	if dropped, parseErr = cfg.processValues(results, contentType); parseErr != nil {
+		return nil, parseErr
+	}
 
-		default:
-			err = &ParseError{Status: http.StatusUnsupportedMediaType, Msg: fmt.Sprintf("Content-Type header %s is unsupported", contentType)}
+	if cfg.CaseInsensitiveKeys {
+		results = renameKeys(results, lowercaseKey)
+		if fileOrder != nil {
+			files, fileOrder = renameOrderedFileKeys(files, fileOrder, lowercaseKey)
+		} else {
+			files = renameKeys(files, lowercaseKey)
 		}
+	}
+
+	if cfg.FieldRename != nil {
+		results = renameKeys(results, cfg.renameField)
+		if fileOrder != nil {
+			files, fileOrder = renameOrderedFileKeys(files, fileOrder, cfg.renameField)
+		} else {
+			files = renameKeys(files, cfg.renameField)
+		}
+	}
+
+	if cfg.MaxFields > 0 {
+		if parseErr = validateFieldCount(results, files, cfg.MaxFields); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	if cfg.MaxValueLength > 0 {
+		if parseErr = validateValueLengths(results, cfg.MaxValueLength); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	if !cfg.KeepRawFilenames && contentType == headerValFormMultipart {
+		if parseErr = sanitizeFilenames(files); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	if cfg.NormalizeFilenames {
+		normalizeFilenames(files)
+	}
+
+	if len(cfg.DecodeDataURIFields) > 0 && (contentType == headerValFormURLEncoded || contentType == headerValFormMultipart) {
+		if files, parseErr = decodeDataURIFields(results, files, cfg.DecodeDataURIFields); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	if len(cfg.JSONFileFields) > 0 && contentType == headerValApplicationJSON {
+		if files, parseErr = decodeBase64Fields(results, files, cfg.JSONFileFields); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	if cfg.AllowedFields != nil {
+		if parseErr = validateAllowedFields(results, files, cfg.AllowedFields); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	if cfg.RejectUnsafeKeyRunes {
+		if parseErr = validateKeyRunes(results, files); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	var rejectedFiles []FileRejection
+	if checks := cfg.fileChecks(); len(checks) > 0 {
+		if cfg.RejectFilesIndividually {
+			files, fileOrder, rejectedFiles = removeRejectedFiles(files, fileOrder, checks)
+		} else if parseErr = validateFiles(files, checks); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	if parseErr = validateDateFields(results, cfg.DateFields); parseErr != nil {
+		return nil, parseErr
+	}
+
+	if !parsed && body.count() < cfg.MinBodySize {
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Request body must be at least %d bytes", cfg.MinBodySize)}
+	}
+
+	if cfg.OnEmptyResult == EmptyResultError && len(results) == 0 && len(files) == 0 {
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Form contains no values or files"}
+	}
+
+	var fileHashes map[string][]FileHash
+	if cfg.HashFiles && len(files) > 0 {
+		if fileHashes, parseErr = hashFiles(files); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	form := &Form{Values: results, Files: files, FileHashes: fileHashes, RejectedFiles: rejectedFiles, fileOrder: fileOrder, dropped: dropped, multipartForm: r.MultipartForm}
+	if contentType == headerValFormMultipart {
+		_, params, _ := mime.ParseMediaType(r.Header.Get(headerKeyContentType))
+		form.boundary = params["boundary"]
+	}
+
+	return form, nil
+}
+
+// processValues validates and transforms the values of the results in place, returning the
+// sorted names of the unanswered fields it removed
+func (cfg Config) processValues(results map[string][]string, contentType string) (dropped []string, parseErr *ParseError) {
+	if cfg.RejectNullBytes {
+		if parseErr = validateNoNullBytes(results); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	if cfg.RequireValidUTF8 {
+		if parseErr = validateUTF8(results); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	if cfg.TrimSpace {
+		trimValues(results)
+	}
+
+	if cfg.ValueTransform != nil {
+		if parseErr = transformValues(results, cfg.ValueTransform); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+
+	if !cfg.KeepEmptyFields {
+		if contentType == headerValFormURLEncoded || contentType == headerValFormMultipart || contentType == headerValTextPlain || contentType == headerValTextCSV {
+			dropped = reduceUnansweredFields(results)
+		} else if cfg.TrimSpace {
+			// the other formats reject empty values while parsing, before they were trimmed
+			if parseErr = validateNoEmptyValues(results); parseErr != nil {
+				return nil, parseErr
+			}
+		}
+	}
+
+	return dropped, nil
+}
+
+// bodyLimit returns the maximum size of a request body of contentType
+func (cfg Config) bodyLimit(contentType string) int64 {
+	if contentType != headerValFormMultipart {
+		return cfg.MaxFormSize
+	}
+	if cfg.OnMemoryOverflow == MemoryOverflowReject && cfg.MaxMemory < cfg.MaxFormWithFilesSize {
+		// a body no bigger than maxMemory can always be parsed without writing files to disk
+		return cfg.MaxMemory
+	}
+	return cfg.MaxFormWithFilesSize
+}
+
+// bufferBody reads the whole request body, up to limit bytes, replacing it with a reader over
+// the buffered bytes. GetBody is set to return a new reader over them, so the body can be read
+// again after parsing.
+func bufferBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, *ParseError) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		if isRequestTooLarge(err) {
+			return nil, &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}
+		}
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Request body could not be read", Err: err}
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
 
-		return results, files, err
+// validateMethod rejects requests whose method isn't allowed to carry a form with a 405
+func (cfg Config) validateMethod(r *http.Request) *ParseError {
+	method := r.Method
+	if method == "" {
+		method = http.MethodGet
 	}
+
+	allowed := !containsString(methodsWithoutBody, method)
+	if cfg.AllowedMethods != nil {
+		allowed = containsString(cfg.AllowedMethods, method)
+	}
+
+	if !allowed {
+		return &ParseError{Status: http.StatusMethodNotAllowed, Msg: fmt.Sprintf("Method %s cannot be used to submit a form", method)}
+	}
+	return nil
 }
 
 // isMultipartFormHeader returns if the content-type header is multipart/form-data.
@@ -78,129 +516,530 @@ func isMultipartFormHeader(contentType string) bool {
 	return strings.HasPrefix(contentType, headerValFormMultipart)
 }
 
+// supportedContentTypes are the content types returned by getContentType that have a parser
+var supportedContentTypes = []string{
+	headerValApplicationJSON,
+	headerValApplicationXML,
+	headerValTextXML,
+	headerValTextCSV,
+	headerValFormURLEncoded,
+	headerValFormMultipart,
+}
+
+// ContentType returns the Content-Type of the request as it is used to choose a parser, with
+// any multipart/form-data boundary removed. A *ParseError with a 415 status is returned when
+// the header is missing or the content type is unsupported.
+func ContentType(r *http.Request) (string, error) {
+	contentType := getContentType(r.Header)
+	if contentType == "" {
+		return "", missingContentTypeError()
+	}
+	if !isSupportedContentType(contentType) {
+		return "", unsupportedContentTypeError(contentType)
+	}
+	return contentType, nil
+}
+
+func missingContentTypeError() *ParseError {
+	return &ParseError{Status: http.StatusUnsupportedMediaType, Msg: "Content-Type header is required"}
+}
+
+func unsupportedContentTypeError(contentType string) *ParseError {
+	return &ParseError{Status: http.StatusUnsupportedMediaType, Msg: fmt.Sprintf("Content-Type header %s is unsupported", contentType)}
+}
+
 func getContentType(header http.Header) string {
 	contentType := header.Get(headerKeyContentType)
 	if isMultipartFormHeader(contentType) {
 		return headerValFormMultipart
 	}
+	// parameters of registered content types, such as the proto parameter naming the message
+	// type of a protobuf body, are read by their BodyParser
+	if registered := registeredContentType(contentType); registered != "" {
+		return registered
+	}
 	return contentType
 }
 
+// getContentType returns the content type of the request like getContentType, first applying
+// any SniffContentType, DefaultContentType and ContentTypeAliases. An aliased Content-Type header is rewritten to the canonical media type,
+// keeping its parameters, so the net/http form parsers accept the body.
+func (cfg Config) getContentType(r *http.Request) string {
+	if cfg.SniffContentType && r.Header.Get(headerKeyContentType) == "" {
+		if sniffed := sniffBodyContentType(r); sniffed != "" {
+			r.Header.Set(headerKeyContentType, sniffed)
+		}
+	}
+	if cfg.DefaultContentType != "" && r.Header.Get(headerKeyContentType) == "" {
+		r.Header.Set(headerKeyContentType, cfg.DefaultContentType)
+	}
+	if len(cfg.ContentTypeAliases) > 0 {
+		mediaType, params, err := mime.ParseMediaType(r.Header.Get(headerKeyContentType))
+		if canonical, ok := cfg.ContentTypeAliases[mediaType]; ok && err == nil {
+			r.Header.Set(headerKeyContentType, mime.FormatMediaType(canonical, params))
+			return canonical
+		}
+	}
+	return getContentType(r.Header)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// checksFiles reports whether any option checking uploaded files is set, so a request must be
+// able to carry files for RequireMultipartForFiles
+func (cfg Config) checksFiles() bool {
+	return cfg.MaxFiles > 0 || len(cfg.AllowedFileExtensions) > 0 || len(cfg.AllowedFileContentTypes) > 0 ||
+		len(cfg.FileSignatureChecks) > 0 || cfg.RejectEmptyFiles || cfg.ScanFile != nil
+}
+
+// countingReader counts the bytes read from the wrapped request body
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// count returns the number of bytes read so far
+func (cr *countingReader) count() int64 {
+	return cr.n
+}
+
+// contextReader stops reading once its context is done, returning and recording the
+// context's error, so reading a body is abandoned when the request is cancelled
+type contextReader struct {
+	io.ReadCloser
+	ctx context.Context
+	err error
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		cr.err = err
+		return 0, err
+	}
+	return cr.ReadCloser.Read(p)
+}
+
+// isRequestTooLarge returns if err was caused by reading past the limit of a http.MaxBytesReader
+func isRequestTooLarge(err error) bool {
+	return strings.Contains(err.Error(), "http: request body too large")
+}
+
 // ParseError is the error returned from parsing the request that can be used
 // to produce a http error response with a status and message
 type ParseError struct {
 	Status int
 	Msg    string
+
+	// Err is the underlying error, if any, for errors caused by a failure outside the request
+	// such as a file callback. It is never included in the message sent to the client.
+	Err error
+
+	// BodySize is the number of bytes read from the request body before parsing failed
+	BodySize int64
 }
 
 func (pe *ParseError) Error() string {
 	return pe.Msg
 }
 
-func parseApplicationJSON(reader io.Reader) (results map[string][]string, err *ParseError) {
+// Unwrap returns the underlying error, for use with errors.Is and errors.As
+func (pe *ParseError) Unwrap() error {
+	return pe.Err
+}
+
+func (cfg Config) parseApplicationJSON(reader io.Reader) (results map[string][]string, err *ParseError) {
 	dec := json.NewDecoder(reader)
 	jsonContent := map[string]interface{}{}
 	decodeErr := dec.Decode(&jsonContent)
 	if decodeErr != nil {
-		var syntaxError *json.SyntaxError
+		return nil, jsonDecodeError(decodeErr)
+	}
 
-		switch {
-		case errors.As(decodeErr, &syntaxError):
-			return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Request body contains badly-formed JSON (at position %d)", syntaxError.Offset)}
+	secondDecodeErr := dec.Decode(&struct{}{})
+	if secondDecodeErr != io.EOF {
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Request body must only contain a single JSON object"}
+	}
+
+	if cfg.JSONValidator != nil {
+		if validationErr := cfg.JSONValidator(jsonContent); validationErr != nil {
+			var parseErr *ParseError
+			if errors.As(validationErr, &parseErr) {
+				return nil, parseErr
+			}
+			return nil, &ParseError{Status: http.StatusBadRequest, Msg: "JSON object is invalid", Err: validationErr}
+		}
+	}
 
-		case errors.Is(decodeErr, io.ErrUnexpectedEOF):
-			return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Request body contains badly-formed JSON"}
+	return cfg.ParseMapInterface(jsonContent)
+}
 
-		case errors.Is(decodeErr, io.EOF):
-			return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Request body must not be empty"}
+// jsonDecodeError converts an error from decoding a JSON request body into a *ParseError
+func jsonDecodeError(decodeErr error) *ParseError {
+	var syntaxError *json.SyntaxError
+	var unmarshalTypeError *json.UnmarshalTypeError
 
-		case decodeErr.Error() == "http: request body too large":
-			return nil, &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}
+	switch {
+	case errors.As(decodeErr, &syntaxError):
+		return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Request body contains badly-formed JSON (at position %d)", syntaxError.Offset)}
 
-		default:
-			return nil, &ParseError{Status: http.StatusInternalServerError, Msg: "JSON parsing error"}
+	case errors.As(decodeErr, &unmarshalTypeError):
+		if unmarshalTypeError.Field == "" {
+			return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Request body contains an invalid JSON %s (at position %d)", unmarshalTypeError.Value, unmarshalTypeError.Offset)}
 		}
-	}
+		return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Request body contains an invalid value for field "%s" (at position %d)`, unmarshalTypeError.Field, unmarshalTypeError.Offset)}
 
-	secondDecodeErr := dec.Decode(&struct{}{})
-	if secondDecodeErr != io.EOF {
-		return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Request body must only contain a single JSON object"}
+	case errors.Is(decodeErr, io.ErrUnexpectedEOF):
+		return &ParseError{Status: http.StatusBadRequest, Msg: "Request body contains badly-formed JSON"}
+
+	case errors.Is(decodeErr, io.EOF):
+		return &ParseError{Status: http.StatusBadRequest, Msg: "Request body must not be empty"}
+
+	case isRequestTooLarge(decodeErr):
+		return &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}
+
+	default:
+		return &ParseError{Status: http.StatusInternalServerError, Msg: "JSON parsing error"}
 	}
+}
+
+// ParseMapInterface flattens an already decoded JSON object into form values, applying the
+// same rules as a JSON request body: values must be non-empty strings or arrays of strings,
+// and empty arrays, nested objects, and other scalars are rejected with a 400
+func ParseMapInterface(mapInterface map[string]interface{}) (results map[string][]string, err *ParseError) {
+	return defaultConfig().ParseMapInterface(mapInterface)
+}
+
+// ParseMapInterface operates the same as ParseMapInterface, applying the JSON value options set
+// in the Config such as CoerceScalars and EmptyArrayStrategy
+func (cfg Config) ParseMapInterface(mapInterface map[string]interface{}) (results map[string][]string, err *ParseError) {
+	return cfg.flattenMap(mapInterface, "JSON object")
+}
 
-	return parseMapInterface(jsonContent)
+// FlattenMap operates the same as ParseMapInterface for a document decoded from another format
+// by a BodyParser, naming it documentName in error messages, e.g. "CBOR map"
+func (cfg Config) FlattenMap(mapInterface map[string]interface{}, documentName string) (results map[string][]string, err *ParseError) {
+	return cfg.flattenMap(mapInterface, documentName)
 }
 
-func parseMapInterface(mapInterface map[string]interface{}) (results map[string][]string, err *ParseError) {
-	results = make(map[string][]string)
+// ToMapInterface converts form values into the shape of a decoded JSON object, the inverse of
+// ParseMapInterface. Fields with a single value become a string and fields with several values
+// a []string, so a submission encoded back to JSON has the shape the client sent.
+func ToMapInterface(results map[string][]string) map[string]interface{} {
+	return Config{FlattenSingleValues: true}.ToMapInterface(results)
+}
+
+// ToMapInterface operates the same as ToMapInterface, but fields with a single value only become
+// a string if FlattenSingleValues is set, and are otherwise a []string like multi-value fields
+func (cfg Config) ToMapInterface(results map[string][]string) map[string]interface{} {
+	mapInterface := make(map[string]interface{}, len(results))
+	for field, values := range results {
+		if cfg.FlattenSingleValues && len(values) == 1 {
+			mapInterface[field] = values[0]
+			continue
+		}
+		mapInterface[field] = append([]string{}, values...)
+	}
+	return mapInterface
+}
+
+// flattenMap applies the JSON value rules to a decoded document of any format, documentName
+// describes the document in error messages, e.g. "JSON object"
+func (cfg Config) flattenMap(mapInterface map[string]interface{}, documentName string) (results map[string][]string, err *ParseError) {
 	if len(mapInterface) == 0 {
-		return nil, &ParseError{Status: http.StatusBadRequest, Msg: `JSON object contains no fields`}
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`%s contains no fields`, documentName)}
 	}
+	results = make(map[string][]string, len(mapInterface))
 
-	for key, interfaceValue := range mapInterface {
-		switch value := interfaceValue.(type) {
-		// string unmarshals JSON strings
-		case string:
-			if value == "" {
-				return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`JSON object contains invalid value for field "%s", cannot use an empty string`, key)}
+	keys := make([]string, 0, len(mapInterface))
+	for key := range mapInterface {
+		keys = append(keys, key)
+	}
+	if cfg.CollectAllErrors {
+		// every error is listed in the message, in a stable order
+		sort.Strings(keys)
+	}
+
+	var invalid []string
+	for _, key := range keys {
+		values, keep, parseErr := cfg.flattenValue(key, mapInterface[key], documentName)
+		if parseErr != nil {
+			if !cfg.CollectAllErrors {
+				return nil, parseErr
 			}
-			results[key] = []string{value}
+			invalid = append(invalid, parseErr.Msg)
+			continue
+		}
+		if keep {
+			results[key] = values
+		}
+	}
 
-		// []interface{} unmarshals JSON arrays
-		case []interface{}:
-			if len(value) == 0 {
-				return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`JSON object contains invalid value for field "%s", cannot use an empty array`, key)}
+	if len(invalid) > 0 {
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: strings.Join(invalid, "; ")}
+	}
+	return results, nil
+}
+
+// flattenValue applies the JSON value rules to the value of a single field, returning the
+// field's values and if the field should be kept in the results
+func (cfg Config) flattenValue(key string, interfaceValue interface{}, documentName string) (values []string, keep bool, err *ParseError) {
+	switch value := interfaceValue.(type) {
+	// string unmarshals JSON strings
+	case string:
+		if value == "" && !cfg.KeepEmptyFields {
+			return nil, false, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`%s contains invalid value for field "%s", cannot use an empty string`, documentName, key)}
+		}
+		return []string{value}, true, nil
+
+	// []interface{} unmarshals JSON arrays
+	case []interface{}:
+		if len(value) == 0 {
+			switch cfg.EmptyArrayStrategy {
+			case EmptyArrayOmit:
+				return nil, false, nil
+			case EmptyArrayKeep:
+				return []string{}, true, nil
+			default:
+				return nil, false, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`%s contains invalid value for field "%s", cannot use an empty array`, documentName, key)}
 			}
+		}
 
-			arrResults := []string{}
-			for _, value := range value {
-				strValue, ok := value.(string)
-				if !ok {
-					return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`JSON object contains invalid array for field "%s", array values must be exclusively strings`, key)}
-				}
-				arrResults = append(arrResults, strValue)
+		if cfg.MaxArrayLen > 0 && len(value) > cfg.MaxArrayLen {
+			return nil, false, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`%s contains invalid array for field "%s", arrays can contain at most %d values`, documentName, key, cfg.MaxArrayLen)}
+		}
+
+		arrResults := make([]string, 0, len(value))
+		for i, value := range value {
+			strValue, ok := value.(string)
+			if !ok {
+				strValue, ok = cfg.coerceScalar(value)
+			}
+			if !ok {
+				return nil, false, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`%s contains invalid array for field "%s", array element %d is %s, array values must be exclusively strings`, documentName, key, i, describeValueType(value))}
 			}
-			results[key] = arrResults
+			arrResults = append(arrResults, strValue)
+		}
+		return arrResults, true, nil
 
-		// reject all other JSON types
-		default:
-			return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`JSON object contains invalid value for field "%s", values must be string or []string types`, key)}
+	// reject all other JSON types, unless they are scalars being coerced to strings
+	default:
+		if coerced, ok := cfg.coerceScalar(value); ok {
+			return []string{coerced}, true, nil
 		}
+		return nil, false, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`%s contains invalid value for field "%s", values must be string or []string types`, documentName, key)}
 	}
+}
 
-	return results, nil
+// describeValueType names the type of a decoded value in error messages, e.g. "an object"
+func describeValueType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case float64, float32, int, int64, uint64, json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	default:
+		return fmt.Sprintf("an unsupported %T", value)
+	}
+}
+
+// coerceScalar converts numbers and booleans to the string representation they would have
+// in a URL encoded form, if CoerceScalars is set. Integers only occur in non-JSON documents
+// such as CBOR and YAML, where they're decoded separately from floats, and json.Number only in
+// objects given to ParseMapInterface by callers decoding with UseNumber.
+func (cfg Config) coerceScalar(value interface{}) (string, bool) {
+	if !cfg.CoerceScalars {
+		return "", false
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case json.Number:
+		return v.String(), true
+	default:
+		return "", false
+	}
 }
 
-func parseFormURLEncoded(r *http.Request) (results map[string][]string, err *ParseError) {
+func (cfg Config) parseFormURLEncoded(r *http.Request) (results map[string][]string, err *ParseError) {
+	if cfg.AllowSemicolonSeparators && r.PostForm == nil {
+		if err = replaceSemicolonSeparators(r); err != nil {
+			return nil, err
+		}
+	}
+
 	// Body reader size is capped at 10MB when using ParseForm()
 	parseFormErr := r.ParseForm()
 	if parseFormErr != nil {
+		if isRequestTooLarge(parseFormErr) {
+			return nil, &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}
+		}
 		return nil, &ParseError{Status: http.StatusBadRequest, Msg: `Invalid URL encoded form`}
 	}
 
-	results = r.Form
-	reduceUnansweredFields(results)
+	// the parsed form is copied so r.PostForm keeps the values as sent, letting the request be
+	// parsed again. r.Form holds the body values of each field in the order sent, followed by its
+	// query string values.
+	form := r.PostForm
+	if cfg.IncludeQueryParams {
+		form = r.Form
+	}
+	if len(form) == 0 {
+		// nothing to copy or check, a new map is still returned so callers can add to it
+		return map[string][]string{}, nil
+	}
+	results = copyFields(form)
+	if cfg.MaxDecodedValueBytes > 0 {
+		for field, values := range results {
+			for _, value := range values {
+				if len(value) > cfg.MaxDecodedValueBytes {
+					return nil, &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: fmt.Sprintf(`URL encoded form field "%s" has a decoded value larger than %d bytes`, field, cfg.MaxDecodedValueBytes)}
+				}
+			}
+		}
+	}
+
+	if cfg.ExpandJSONArrayValues {
+		for field, values := range results {
+			if len(values) != 1 || !strings.HasPrefix(values[0], "[") {
+				continue
+			}
+
+			var expanded []string
+			if json.Unmarshal([]byte(values[0]), &expanded) != nil {
+				return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`URL encoded form field "%s" must be a JSON array of strings`, field)}
+			}
+			results[field] = expanded
+		}
+	}
 
 	return results, nil
 }
 
+// replaceSemicolonSeparators replaces the body with a copy using "&" in place of every ";",
+// which net/http no longer accepts as a separator. A ";" within a value must be percent-encoded
+// as "%3B", so every literal ";" in a URL encoded body separates fields.
+func replaceSemicolonSeparators(r *http.Request) *ParseError {
+	body, readErr := ioutil.ReadAll(r.Body)
+	if readErr != nil {
+		if isRequestTooLarge(readErr) {
+			return &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}
+		}
+		return &ParseError{Status: http.StatusBadRequest, Msg: `Invalid URL encoded form`}
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(bytes.ReplaceAll(body, []byte(";"), []byte("&"))))
+	return nil
+}
+
 func parseFormMultipart(r *http.Request, maxMemory int64) (results map[string][]string, files map[string][]*multipart.FileHeader, err *ParseError) {
 	parseFormErr := r.ParseMultipartForm(maxMemory)
 	if parseFormErr != nil {
-		return nil, nil, &ParseError{Status: http.StatusBadRequest, Msg: `Invalid URL encoded form`}
+		if isRequestTooLarge(parseFormErr) {
+			return nil, nil, &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}
+		}
+		return nil, nil, &ParseError{Status: http.StatusBadRequest, Msg: `Invalid multipart form`}
 	}
 
-	results = r.PostForm
-	reduceUnansweredFields(results)
+	// the parsed form is copied so r.MultipartForm keeps the fields as sent, letting the request
+	// be parsed again
+	return copyFields(r.PostForm), copyFields(r.MultipartForm.File), nil
+}
 
-	return results, r.MultipartForm.File, nil
+// copyFields returns a copy of the map, copying the slices of values so they can be modified
+func copyFields[V any](m map[string][]V) map[string][]V {
+	copied := make(map[string][]V, len(m))
+	for key, values := range m {
+		copied[key] = append([]V(nil), values...)
+	}
+	return copied
+}
+
+// renameKeys returns the map with every key replaced by its new name from rename, dropping keys
+// rename doesn't keep and concatenating the values of keys given the same name in the
+// alphabetical order of the original keys
+func renameKeys[V any](m map[string][]V, rename func(key string) (string, bool)) map[string][]V {
+	if m == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	renamed := make(map[string][]V, len(m))
+	for _, key := range keys {
+		if name, keep := rename(key); keep {
+			renamed[name] = append(renamed[name], m[key]...)
+		}
+	}
+	return renamed
+}
+
+// lowercaseKey renames keys to lowercase for renameKeys
+func lowercaseKey(key string) (string, bool) {
+	return strings.ToLower(key), true
 }
 
 // Unanswered fields in URL encoded and multipart forms are encoded as an empty []string,
-// this function removes the empty []string from the results
-func reduceUnansweredFields(results map[string][]string) {
+// this function removes the empty []string from the results and returns the sorted names of
+// the removed fields
+func reduceUnansweredFields(results map[string][]string) (dropped []string) {
+	// the unanswered fields are counted first so dropped is allocated once, and deleted after
+	// ranging over results rather than while ranging over it
+	unanswered := 0
+	for _, values := range results {
+		if isUnanswered(values) {
+			unanswered++
+		}
+	}
+	if unanswered == 0 {
+		return nil
+	}
+
+	dropped = make([]string, 0, unanswered)
 	for field, values := range results {
-		if values == nil || len(values) == 0 || (len(values) == 1 && values[0] == "") {
-			delete(results, field)
+		if isUnanswered(values) {
+			dropped = append(dropped, field)
 		}
 	}
+	for _, field := range dropped {
+		delete(results, field)
+	}
+	sort.Strings(dropped)
+	return dropped
+}
+
+// isUnanswered reports if a field has no values, or only a single empty value
+func isUnanswered(values []string) bool {
+	return len(values) == 0 || (len(values) == 1 && values[0] == "")
 }
@@ -2,6 +2,8 @@ package formhandler
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -11,6 +13,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
 	"testing"
 
@@ -21,7 +24,6 @@ import (
 TODO: these tests are not exhaustive, would be nice to:
 - generate random form entries using fuzzing
 - check the returned errors are the expected type for a given error condition (this would require sentinel error types with optional wrapping with additional error information)
-- run benchmarks, in particular the reduceUnansweredFields calls in combination with ParseForm is low hanging code to optimise, as we iterate through all fields twice
 */
 
 func TestGetFormContent_JSONEncoded(t *testing.T) {
@@ -206,6 +208,233 @@ func TestGetFormContent_JSONEncoded(t *testing.T) {
 	}
 }
 
+func TestConfigDefaults(t *testing.T) {
+	assert.Equal(t, defaultConfig(), Config{}.withDefaults())
+	assert.Equal(t, int64(64), Config{MaxFormSize: 64}.withDefaults().MaxFormSize)
+
+	// a zero Config parses requests with the default limits
+	r, err := constructJSONEncodedForm(`{"field1": "value1"}`)
+	assert.NoError(t, err)
+	results, _, err := Config{}.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}}, results)
+
+	r, err = constructJSONEncodedForm(`{"field1": "` + strings.Repeat("a", megabyte) + `"}`)
+	assert.NoError(t, err)
+	_, _, err = Config{}.GetFormContent(httptest.NewRecorder(), r)
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, pe.Status)
+	}
+
+	r, err = constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "file.txt", value: "content"}})
+	assert.NoError(t, err)
+	_, files, err := GetFormContentWithConfig(0, 0, 0)(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Len(t, files["file1"], 1)
+}
+
+func TestGetFormContent_JSONTypeErrors(t *testing.T) {
+	// decoding into a map only gives type errors for JSON values that aren't objects, which
+	// previously fell through to a 500
+	var typeErrorTests = []struct {
+		testName    string
+		body        string
+		expectedMsg string
+	}{
+		{"top level array", `["value1"]`, "Request body contains an invalid JSON array (at position 1)"},
+		{"top level string", `"value1"`, "Request body contains an invalid JSON string (at position 8)"},
+		{"top level number", `12`, "Request body contains an invalid JSON number (at position 2)"},
+	}
+
+	for _, tt := range typeErrorTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructJSONEncodedForm(tt.body)
+			assert.NoError(t, err)
+
+			_, _, err = GetFormContent(httptest.NewRecorder(), r)
+			var pe *ParseError
+			if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+				assert.Equal(t, http.StatusBadRequest, pe.Status)
+				assert.Equal(t, tt.expectedMsg, pe.Msg)
+			}
+		})
+	}
+}
+
+func TestGetFormContent_JSONArrayElementErrors(t *testing.T) {
+	var elementErrorTests = []struct {
+		testName    string
+		body        string
+		expectedMsg string
+	}{
+		{"object", `{"field1": [{"x": 1}]}`, `JSON object contains invalid array for field "field1", array element 0 is an object, array values must be exclusively strings`},
+		{"number", `{"field1": ["a", 1]}`, `JSON object contains invalid array for field "field1", array element 1 is a number, array values must be exclusively strings`},
+		{"boolean", `{"field1": ["a", "b", true]}`, `JSON object contains invalid array for field "field1", array element 2 is a boolean, array values must be exclusively strings`},
+		{"null", `{"field1": [null]}`, `JSON object contains invalid array for field "field1", array element 0 is null, array values must be exclusively strings`},
+		{"nested array", `{"field1": [["a"]]}`, `JSON object contains invalid array for field "field1", array element 0 is an array, array values must be exclusively strings`},
+	}
+
+	for _, tt := range elementErrorTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructJSONEncodedForm(tt.body)
+			assert.NoError(t, err)
+
+			_, _, err = GetFormContent(httptest.NewRecorder(), r)
+			var pe *ParseError
+			if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+				assert.Equal(t, http.StatusBadRequest, pe.Status)
+				assert.Equal(t, tt.expectedMsg, pe.Msg)
+			}
+		})
+	}
+}
+
+func TestCollectAllErrors(t *testing.T) {
+	body := `{"a": "", "b": [], "c": {"d": "e"}, "f": 1, "g": "valid"}`
+
+	// the default fails on the first invalid field
+	r, err := constructJSONEncodedForm(body)
+	assert.NoError(t, err)
+	_, _, err = GetFormContent(httptest.NewRecorder(), r)
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, 1, strings.Count(pe.Msg, "JSON object contains"))
+	}
+
+	cfg := defaultConfig()
+	cfg.CollectAllErrors = true
+	r, err = constructJSONEncodedForm(body)
+	assert.NoError(t, err)
+	results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.Nil(t, results)
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusBadRequest, pe.Status)
+		assert.Equal(t, `JSON object contains invalid value for field "a", cannot use an empty string; `+
+			`JSON object contains invalid value for field "b", cannot use an empty array; `+
+			`JSON object contains invalid value for field "c", values must be string or []string types; `+
+			`JSON object contains invalid value for field "f", values must be string or []string types`, pe.Msg)
+	}
+
+	// valid bodies are unaffected
+	r, err = constructJSONEncodedForm(`{"g": "valid"}`)
+	assert.NoError(t, err)
+	results, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"g": {"valid"}}, results)
+}
+
+func TestEmptyArrayStrategy(t *testing.T) {
+	for _, tt := range []struct {
+		strategy             EmptyArrayStrategy
+		expectedValuesOutput map[string][]string
+		expectedError        bool
+	}{
+		{EmptyArrayReject, nil, true},
+		{EmptyArrayOmit, map[string][]string{"field1": {"value1"}}, false},
+		{EmptyArrayKeep, map[string][]string{"field1": {"value1"}, "tags": {}}, false},
+	} {
+		cfg := defaultConfig()
+		cfg.EmptyArrayStrategy = tt.strategy
+
+		r, err := constructJSONEncodedForm(`{"field1": "value1", "tags": []}`)
+		assert.NoError(t, err)
+		results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+		assert.Equal(t, tt.expectedValuesOutput, results)
+		assert.Equal(t, tt.expectedError, err != nil)
+	}
+}
+
+func TestCoerceScalars(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.CoerceScalars = true
+
+	var coerceTests = []struct {
+		testName             string
+		json                 string
+		expectedValuesOutput map[string][]string
+	}{
+		{"integer", `{"age": 30}`, map[string][]string{"age": {"30"}}},
+		{"float", `{"height": 1.85}`, map[string][]string{"height": {"1.85"}}},
+		{"large number", `{"big": 1e21}`, map[string][]string{"big": {"1000000000000000000000"}}},
+		{"boolean", `{"member": true}`, map[string][]string{"member": {"true"}}},
+		{"mixed array", `{"choices": ["a", 1, false]}`, map[string][]string{"choices": {"a", "1", "false"}}},
+		{"null", `{"field1": null}`, nil},
+		{"null in array", `{"choices": ["a", null]}`, nil},
+		{"object", `{"field1": {"a": 1}}`, nil},
+	}
+
+	for _, tt := range coerceTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructJSONEncodedForm(tt.json)
+			assert.NoError(t, err)
+
+			results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+			assert.Equal(t, tt.expectedValuesOutput == nil, err != nil)
+		})
+	}
+}
+
+func TestParseMapInterface(t *testing.T) {
+	var parseMapTests = []struct {
+		testName             string
+		mapInterface         map[string]interface{}
+		expectedValuesOutput map[string][]string
+	}{
+		{"strings", map[string]interface{}{"field1": "value1", "field2": []interface{}{"a", "b"}}, map[string][]string{"field1": {"value1"}, "field2": {"a", "b"}}},
+		{"empty", map[string]interface{}{}, nil},
+		{"empty string", map[string]interface{}{"field1": ""}, nil},
+		{"empty array", map[string]interface{}{"field1": []interface{}{}}, nil},
+		{"nested object", map[string]interface{}{"field1": map[string]interface{}{"a": "b"}}, nil},
+		{"number", map[string]interface{}{"field1": json.Number("30")}, nil},
+	}
+
+	for _, tt := range parseMapTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			results, pe := ParseMapInterface(tt.mapInterface)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+			if tt.expectedValuesOutput == nil {
+				if assert.NotNil(t, pe) {
+					assert.Equal(t, http.StatusBadRequest, pe.Status)
+				}
+			} else {
+				assert.Nil(t, pe)
+			}
+		})
+	}
+
+	// numbers decoded with UseNumber are coerced like float64s
+	cfg := defaultConfig()
+	cfg.CoerceScalars = true
+	results, pe := cfg.ParseMapInterface(map[string]interface{}{"age": json.Number("30"), "ids": []interface{}{json.Number("1.5")}})
+	assert.Nil(t, pe)
+	assert.Equal(t, map[string][]string{"age": {"30"}, "ids": {"1.5"}}, results)
+}
+
+func TestToMapInterface(t *testing.T) {
+	results := map[string][]string{"field1": {"value1"}, "field2": {"a", "b"}, "field3": {}}
+
+	assert.Equal(t, map[string]interface{}{"field1": "value1", "field2": []string{"a", "b"}, "field3": []string{}}, ToMapInterface(results))
+	assert.Equal(t, map[string]interface{}{"field1": []string{"value1"}, "field2": []string{"a", "b"}, "field3": []string{}}, defaultConfig().ToMapInterface(results))
+
+	cfg := defaultConfig()
+	cfg.FlattenSingleValues = true
+	assert.Equal(t, ToMapInterface(results), cfg.ToMapInterface(results))
+
+	// values encoded to JSON parse back to the same results
+	delete(results, "field3")
+	for _, mapInterface := range []map[string]interface{}{ToMapInterface(results), defaultConfig().ToMapInterface(results)} {
+		encoded, err := json.Marshal(mapInterface)
+		assert.NoError(t, err)
+		r, err := constructJSONEncodedForm(string(encoded))
+		assert.NoError(t, err)
+		parsed, _, err := GetFormContent(httptest.NewRecorder(), r)
+		assert.NoError(t, err)
+		assert.Equal(t, results, parsed)
+	}
+}
+
 func TestGetFormContent_URLEncoded(t *testing.T) {
 	var formContentTests = []struct {
 		testName               string
@@ -481,6 +710,626 @@ func TestGetFormContent_Multipart(t *testing.T) {
 	}
 }
 
+func TestGetFormContent_MultipartRepeatedFields(t *testing.T) {
+	// with a 64 byte memory limit the small file is held in memory
+	// and the large file is written to a temporary file on disk
+	cfg := Config{MaxFormSize: megabyte, MaxFormWithFilesSize: megabyte, MaxMemory: 64}
+	largeContent := strings.Repeat("large file content ", 100)
+
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "field1", value: "value1"},
+		{field: "files", fileName: "small.txt", value: "small"},
+		{field: "field1", value: strings.Repeat("v", 1024)},
+		{field: "files", fileName: "large.txt", value: largeContent},
+	})
+	assert.NoError(t, err)
+
+	results, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	defer r.MultipartForm.RemoveAll()
+
+	assert.Equal(t, map[string][]string{"field1": {"value1", strings.Repeat("v", 1024)}}, results)
+	if assert.Len(t, files["files"], 2) {
+		for i, expected := range []string{"small", largeContent} {
+			f, err := files["files"][i].Open()
+			assert.NoError(t, err)
+			_, onDisk := f.(*os.File)
+			assert.Equal(t, i == 1, onDisk, "only the large file should be stored on disk")
+			content, err := ioutil.ReadAll(f)
+			assert.NoError(t, err)
+			f.Close()
+			assert.Equal(t, expected, string(content))
+		}
+	}
+}
+
+func TestMaxDecodedValueBytes(t *testing.T) {
+	cfg := Config{MaxFormSize: megabyte, MaxFormWithFilesSize: megabyte, MaxMemory: megabyte, MaxDecodedValueBytes: 8}
+
+	// the encoded value is 12 bytes but only 4 once decoded
+	r, err := http.NewRequest(http.MethodPost, "/", strings.NewReader("field1=%41%42%43%44&field2=short"))
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"ABCD"}, "field2": {"short"}}, results)
+
+	r, err = constructURLEncodedForm(url.Values{"field1": {"short"}, "field2": {"much too long"}})
+	assert.NoError(t, err)
+	results, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.Nil(t, results)
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, pe.Status)
+	assert.Contains(t, pe.Msg, "field2")
+}
+
+func TestFormParseErrors(t *testing.T) {
+	cfg := Config{MaxFormSize: 64, MaxFormWithFilesSize: 256, MaxMemory: 256}
+
+	var parseErrorTests = []struct {
+		testName       string
+		contentType    string
+		body           string
+		expectedStatus int
+		expectedMsg    string
+	}{
+		{"URL encoded too large", "application/x-www-form-urlencoded", "field1=" + strings.Repeat("a", 100), http.StatusRequestEntityTooLarge, "Request body too large"},
+		{"URL encoded malformed", "application/x-www-form-urlencoded", "field1=%zz", http.StatusBadRequest, "Invalid URL encoded form"},
+		{"multipart too large", "multipart/form-data; boundary=xyz", "--xyz\r\nContent-Disposition: form-data; name=\"field1\"\r\n\r\n" + strings.Repeat("a", 300), http.StatusRequestEntityTooLarge, "Request body too large"},
+		{"multipart malformed", "multipart/form-data; boundary=xyz", "not a multipart body", http.StatusBadRequest, "Invalid multipart form"},
+	}
+
+	for _, tt := range parseErrorTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			assert.NoError(t, err)
+			r.Header.Set("Content-Type", tt.contentType)
+
+			_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+			var pe *ParseError
+			if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+				assert.Equal(t, tt.expectedStatus, pe.Status)
+				assert.Equal(t, tt.expectedMsg, pe.Msg)
+			}
+		})
+	}
+}
+
+func TestGetFormContent_ParsedTwice(t *testing.T) {
+	// each option modifies the parsed fields, which must not change the form kept on the request
+	transformCfg := defaultConfig()
+	transformCfg.ValueTransform = func(field, value string) (string, error) { return "<" + value + ">", nil }
+	transformCfg.MinBodySize = 16
+	dataURICfg := defaultConfig()
+	dataURICfg.DecodeDataURIFields = []string{"avatar"}
+	dataURICfg.CaseInsensitiveKeys = true
+
+	var parsedTwiceTests = []struct {
+		testName               string
+		cfg                    Config
+		testRequestConstructor func() (*http.Request, error)
+		expectedValuesOutput   map[string][]string
+		expectedFileFields     []string
+	}{
+		{
+			"URL encoded",
+			transformCfg,
+			func() (*http.Request, error) {
+				return constructURLEncodedForm(url.Values{"field1": {"value1"}, "empty": {""}})
+			},
+			map[string][]string{"field1": {"<value1>"}, "empty": {"<>"}},
+			nil,
+		},
+		{
+			"multipart",
+			transformCfg,
+			func() (*http.Request, error) {
+				return constructMultipartFormParts([]multipartPart{
+					{field: "field1", value: "value1"},
+					{field: "file1", fileName: "one.txt", value: "one"},
+				})
+			},
+			map[string][]string{"field1": {"<value1>"}},
+			[]string{"file1"},
+		},
+		{
+			"multipart with data URI",
+			dataURICfg,
+			func() (*http.Request, error) {
+				return constructMultipartFormParts([]multipartPart{
+					{field: "Field1", value: "value1"},
+					{field: "avatar", value: "data:text/plain;base64,aGk="},
+					{field: "file1", fileName: "one.txt", value: "one"},
+				})
+			},
+			map[string][]string{"field1": {"value1"}},
+			[]string{"avatar", "file1"},
+		},
+	}
+
+	for _, tt := range parsedTwiceTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			cfg := tt.cfg
+			r, err := tt.testRequestConstructor()
+			assert.NoError(t, err)
+
+			for i := 0; i < 2; i++ {
+				results, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+				assert.NoError(t, err, "parse %d", i+1)
+				assert.Equal(t, tt.expectedValuesOutput, results, "parse %d", i+1)
+				var fileFields []string
+				for field := range files {
+					fileFields = append(fileFields, field)
+				}
+				sort.Strings(fileFields)
+				assert.Equal(t, tt.expectedFileFields, fileFields, "parse %d", i+1)
+			}
+		})
+	}
+}
+
+func TestAllowSemicolonSeparators(t *testing.T) {
+	body := "field1=value1;field2=a%3Bb&field3=value3"
+
+	// rejected by default
+	r, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, _, err = GetFormContent(httptest.NewRecorder(), r)
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusBadRequest, pe.Status)
+	}
+
+	cfg := defaultConfig()
+	cfg.AllowSemicolonSeparators = true
+	r, err = http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}, "field2": {"a;b"}, "field3": {"value3"}}, results)
+}
+
+func TestURLEncodedQueryParams(t *testing.T) {
+	var queryParamsTests = []struct {
+		testName             string
+		includeQueryParams   bool
+		expectedValuesOutput map[string][]string
+	}{
+		{"body values only", false, map[string][]string{"field1": {"body1", "body2"}, "field3": {"body3"}}},
+		{"body values before query values", true, map[string][]string{"field1": {"body1", "body2", "query1"}, "field2": {"query2"}, "field3": {"body3"}}},
+	}
+
+	for _, tt := range queryParamsTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.IncludeQueryParams = tt.includeQueryParams
+
+			r, err := http.NewRequest(http.MethodPost, "/?field1=query1&field2=query2", strings.NewReader("field1=body1&field3=body3&field1=body2"))
+			assert.NoError(t, err)
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+		})
+	}
+}
+
+func TestExpandJSONArrayValues(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.ExpandJSONArrayValues = true
+
+	var expandTests = []struct {
+		testName             string
+		values               url.Values
+		expectedValuesOutput map[string][]string
+		expectedError        bool
+	}{
+		{"JSON array", url.Values{"tags": {`["a","b"]`}, "name": {"charlie"}}, map[string][]string{"tags": {"a", "b"}, "name": {"charlie"}}, false},
+		{"empty JSON array", url.Values{"tags": {`[]`}, "name": {"charlie"}}, map[string][]string{"name": {"charlie"}}, false},
+		{"repeated field unchanged", url.Values{"tags": {`["a"]`, `["b"]`}}, map[string][]string{"tags": {`["a"]`, `["b"]`}}, false},
+		{"JSON object unchanged", url.Values{"tags": {`{"a":"b"}`}}, map[string][]string{"tags": {`{"a":"b"}`}}, false},
+		{"invalid JSON", url.Values{"tags": {`["a",`}}, nil, true},
+		{"array of numbers", url.Values{"tags": {`[1,2]`}}, nil, true},
+	}
+
+	for _, tt := range expandTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructURLEncodedForm(tt.values)
+			assert.NoError(t, err)
+
+			results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+			if tt.expectedError {
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, http.StatusBadRequest, pe.Status)
+					assert.Contains(t, pe.Msg, `"tags"`)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestBufferBody(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.BufferBody = true
+	cfg.MaxFormSize = 64
+
+	var bufferTests = []struct {
+		testName       string
+		body           string
+		expectedStatus int
+	}{
+		{"parsed body", `{"field1": "value1"}`, 0},
+		{"rejected body", `{"field1": ""}`, http.StatusBadRequest},
+		{"oversized body", `{"field1": "` + strings.Repeat("a", 64) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range bufferTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructJSONEncodedForm(tt.body)
+			assert.NoError(t, err)
+
+			_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+			if tt.expectedStatus != 0 {
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, tt.expectedStatus, pe.Status)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+			if tt.expectedStatus == http.StatusRequestEntityTooLarge {
+				return
+			}
+
+			// the body can be read again, and again through GetBody
+			body, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.body, string(body))
+			getBody, err := r.GetBody()
+			assert.NoError(t, err)
+			body, err = ioutil.ReadAll(getBody)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.body, string(body))
+		})
+	}
+
+	// multipart bodies are buffered too
+	r, err := constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "file.txt", value: "content"}})
+	assert.NoError(t, err)
+	form, err := cfg.GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Len(t, form.Files["file1"], 1)
+	part, err := multipart.NewReader(r.Body, form.Boundary()).NextPart()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "file.txt", part.FileName())
+	}
+}
+
+func TestOnEmptyResult(t *testing.T) {
+	for _, tt := range []struct {
+		action        EmptyResultAction
+		expectedError bool
+	}{
+		{EmptyResultPass, false},
+		{EmptyResultError, true},
+	} {
+		cfg := Config{MaxFormSize: megabyte, MaxFormWithFilesSize: megabyte, MaxMemory: megabyte, OnEmptyResult: tt.action}
+
+		r, err := constructMultipartFormParts([]multipartPart{{field: "field1", value: ""}})
+		assert.NoError(t, err)
+		results, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+		assert.Empty(t, results)
+		assert.Empty(t, files)
+		if tt.expectedError {
+			var pe *ParseError
+			assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+			assert.Equal(t, http.StatusBadRequest, pe.Status)
+		} else {
+			assert.NoError(t, err)
+		}
+
+		// a form with only a file is not empty
+		r, err = constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "file.txt", value: "content"}})
+		assert.NoError(t, err)
+		_, files, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+	}
+}
+
+func TestRequireMultipartForFiles(t *testing.T) {
+	cfg := Config{MaxFormSize: megabyte, MaxFormWithFilesSize: megabyte, MaxMemory: megabyte, RequireMultipartForFiles: true}
+
+	// without an option checking files any Content-Type is accepted
+	r, err := constructURLEncodedForm(url.Values{"field1": {"value1"}})
+	assert.NoError(t, err)
+	results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}}, results)
+
+	cfg.MaxFiles = 1
+	r, err = constructJSONEncodedForm(`{"field1": "value1"}`)
+	assert.NoError(t, err)
+	results, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.Nil(t, results)
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusBadRequest, pe.Status)
+
+	r, err = constructURLEncodedForm(url.Values{"field1": {"value1"}})
+	assert.NoError(t, err)
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusBadRequest, pe.Status)
+
+	r, err = constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "file.txt", value: "content"}})
+	assert.NoError(t, err)
+	_, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestReduceUnansweredFields(t *testing.T) {
+	results := map[string][]string{
+		"answered":      {"value1"},
+		"multiple":      {"", ""},
+		"empty":         {""},
+		"no values":     {},
+		"nil":           nil,
+		"also answered": {"", "value2"},
+	}
+
+	dropped := reduceUnansweredFields(results)
+	assert.Equal(t, []string{"empty", "nil", "no values"}, dropped)
+	assert.Equal(t, map[string][]string{"answered": {"value1"}, "multiple": {"", ""}, "also answered": {"", "value2"}}, results)
+
+	assert.Nil(t, reduceUnansweredFields(results))
+}
+
+func TestWarnDroppedFields(t *testing.T) {
+	cfg := Config{MaxFormSize: megabyte, MaxFormWithFilesSize: megabyte, MaxMemory: megabyte, WarnDroppedFields: true}
+
+	r, err := constructURLEncodedForm(url.Values{"field1": {"value1"}, "field2": {""}, "field3": {}})
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	results, _, err := cfg.GetFormContent(w, r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}}, results)
+	assert.Equal(t, `199 - "Dropped empty form fields: field2"`, w.Header().Get("Warning"))
+
+	r, err = constructMultipartFormParts([]multipartPart{{field: "field1", value: "value1"}})
+	assert.NoError(t, err)
+	w = httptest.NewRecorder()
+	_, _, err = cfg.GetFormContent(w, r)
+	assert.NoError(t, err)
+	assert.Empty(t, w.Header().Get("Warning"))
+
+	// a form rejected by a later check isn't sent a warning
+	rejecting := cfg
+	rejecting.OnEmptyResult = EmptyResultError
+	r, err = constructURLEncodedForm(url.Values{"field2": {""}})
+	assert.NoError(t, err)
+	w = httptest.NewRecorder()
+	_, _, err = rejecting.GetFormContent(w, r)
+	assert.Error(t, err)
+	assert.Empty(t, w.Header().Get("Warning"))
+
+	rejecting = cfg
+	rejecting.MinBodySize = 1000
+	r, err = constructURLEncodedForm(url.Values{"field1": {"value1"}, "field2": {""}})
+	assert.NoError(t, err)
+	w = httptest.NewRecorder()
+	_, _, err = rejecting.GetFormContent(w, r)
+	assert.Error(t, err)
+	assert.Empty(t, w.Header().Get("Warning"))
+}
+
+func TestKeepEmptyFields(t *testing.T) {
+	cfg := Config{MaxFormSize: megabyte, MaxFormWithFilesSize: megabyte, MaxMemory: megabyte, KeepEmptyFields: true, WarnDroppedFields: true}
+
+	r, err := constructURLEncodedForm(url.Values{"field1": {"value1"}, "field2": {""}})
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	results, _, err := cfg.GetFormContent(w, r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}, "field2": {""}}, results)
+	assert.Empty(t, w.Header().Get("Warning"))
+
+	r, err = constructMultipartFormParts([]multipartPart{{field: "field1", value: ""}})
+	assert.NoError(t, err)
+	results, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {""}}, results)
+
+	r, err = constructJSONEncodedForm(`{"field1":"value1","field2":""}`)
+	assert.NoError(t, err)
+	results, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}, "field2": {""}}, results)
+}
+
+func TestCaseInsensitiveKeys(t *testing.T) {
+	cfg := Config{MaxFormSize: megabyte, MaxFormWithFilesSize: megabyte, MaxMemory: megabyte, CaseInsensitiveKeys: true}
+
+	r, err := constructJSONEncodedForm(`{"email":"a@b.c","Email":["d@e.f"],"NAME":"bob"}`)
+	assert.NoError(t, err)
+	results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"email": {"d@e.f", "a@b.c"}, "name": {"bob"}}, results)
+
+	r, err = constructURLEncodedForm(url.Values{"Email": {"a@b.c"}, "email": {"d@e.f"}, "EMAIL": {""}})
+	assert.NoError(t, err)
+	results, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"email": {"a@b.c", "d@e.f"}}, results)
+
+	r, err = constructMultipartFormParts([]multipartPart{
+		{field: "Name", value: "bob"},
+		{field: "Avatar", fileName: "one.png", value: "one"},
+		{field: "avatar", fileName: "two.png", value: "two"},
+	})
+	assert.NoError(t, err)
+	results, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"name": {"bob"}}, results)
+	if assert.Len(t, files, 1) && assert.Len(t, files["avatar"], 2) {
+		assert.Equal(t, "one.png", files["avatar"][0].Filename)
+		assert.Equal(t, "two.png", files["avatar"][1].Filename)
+	}
+}
+
+func TestFieldRename(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.FieldRename = map[string]string{"first_name": "firstName", "email": "contact", "phone": "contact", "profile_photo": "profilePhoto"}
+
+	r, err := constructJSONEncodedForm(`{"first_name":"charlie","email":"a@b.c","phone":"0123","age":"30"}`)
+	assert.NoError(t, err)
+	results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"firstName": {"charlie"}, "contact": {"a@b.c", "0123"}, "age": {"30"}}, results)
+
+	r, err = constructMultipartFormParts([]multipartPart{
+		{field: "first_name", value: "charlie"},
+		{field: "age", value: "30"},
+		{field: "profile_photo", fileName: "photo.png", value: "photo"},
+		{field: "other_file", fileName: "other.txt", value: "other"},
+	})
+	assert.NoError(t, err)
+	cfg.DropUnmapped = true
+	results, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"firstName": {"charlie"}}, results)
+	if assert.Len(t, files, 1) && assert.Len(t, files["profilePhoto"], 1) {
+		assert.Equal(t, "photo.png", files["profilePhoto"][0].Filename)
+	}
+}
+
+func TestOnMemoryOverflow(t *testing.T) {
+	for _, tt := range []struct {
+		action         MemoryOverflowAction
+		expectedStatus int
+	}{
+		{MemoryOverflowSpillToDisk, 0},
+		{MemoryOverflowReject, http.StatusRequestEntityTooLarge},
+	} {
+		cfg := Config{MaxFormSize: megabyte, MaxFormWithFilesSize: megabyte, MaxMemory: 512, OnMemoryOverflow: tt.action}
+
+		r, err := constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "file.txt", value: strings.Repeat("a", 1024)}})
+		assert.NoError(t, err)
+		_, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+		if tt.expectedStatus == 0 {
+			assert.NoError(t, err)
+			assert.Len(t, files, 1)
+			r.MultipartForm.RemoveAll()
+		} else {
+			var pe *ParseError
+			assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+			assert.Equal(t, tt.expectedStatus, pe.Status)
+		}
+
+		// forms that fit in memory are unaffected
+		r, err = constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "file.txt", value: "small"}})
+		assert.NoError(t, err)
+		_, files, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+	}
+}
+
+func TestAllowedMethods(t *testing.T) {
+	var methodTests = []struct {
+		testName       string
+		allowedMethods []string
+		method         string
+		expectedError  bool
+	}{
+		{"default POST", nil, http.MethodPost, false},
+		{"default PUT", nil, http.MethodPut, false},
+		{"default PATCH", nil, http.MethodPatch, false},
+		{"default GET", nil, http.MethodGet, true},
+		{"default HEAD", nil, http.MethodHead, true},
+		{"default DELETE", nil, http.MethodDelete, true},
+		{"default empty method is GET", nil, "", true},
+		{"allow-list includes DELETE", []string{http.MethodPost, http.MethodDelete}, http.MethodDelete, false},
+		{"allow-list excludes PUT", []string{http.MethodPost, http.MethodDelete}, http.MethodPut, true},
+	}
+
+	for _, tt := range methodTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.AllowedMethods = tt.allowedMethods
+
+			r, err := constructJSONEncodedForm(`{"field1": "value1"}`)
+			assert.NoError(t, err)
+			r.Method = tt.method
+
+			results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+			if tt.expectedError {
+				assert.Nil(t, results)
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, http.StatusMethodNotAllowed, pe.Status)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMethodContentTypes(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.MethodContentTypes = map[string][]string{
+		http.MethodPatch: {"application/json"},
+	}
+
+	r, err := constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "file.txt", value: "content"}})
+	assert.NoError(t, err)
+	r.Method = http.MethodPatch
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusBadRequest, pe.Status)
+
+	r, err = constructJSONEncodedForm(`{"field1": "value1"}`)
+	assert.NoError(t, err)
+	r.Method = http.MethodPatch
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+
+	// methods not in the map are unrestricted
+	r, err = constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "file.txt", value: "content"}})
+	assert.NoError(t, err)
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+}
+
+func TestMinBodySize(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.MinBodySize = 32
+
+	r, err := constructJSONEncodedForm(`{"field1": "value1"}`)
+	assert.NoError(t, err)
+	results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.Nil(t, results)
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusBadRequest, pe.Status)
+
+	r, err = constructJSONEncodedForm(`{"field1": "value1", "field2": "value2"}`)
+	assert.NoError(t, err)
+	results, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+}
+
 func TestInvalidContentType(t *testing.T) {
 	r, err := http.NewRequest(http.MethodPost, "/", nil)
 	assert.NoError(t, err)
@@ -495,6 +1344,44 @@ func TestInvalidContentType(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestContentTypeAliases(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.ContentTypeAliases = map[string]string{
+		"application/vnd.myapp+json":   "application/json",
+		"application/vnd.myapp.form":   "application/x-www-form-urlencoded",
+		"application/vnd.myapp.upload": "multipart/form-data",
+	}
+
+	r, err := constructJSONEncodedForm(`{"field1":"value1"}`)
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "application/vnd.myapp+json; charset=utf-8")
+	results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}}, results)
+
+	r, err = constructURLEncodedForm(url.Values{"field1": {"value1"}})
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "application/vnd.myapp.form")
+	results, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}}, results)
+
+	r, err = constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "one.txt", value: "one"}})
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", strings.Replace(r.Header.Get("Content-Type"), "multipart/form-data", "application/vnd.myapp.upload", 1))
+	_, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Len(t, files["file1"], 1)
+
+	r, err = constructJSONEncodedForm(`{"field1":"value1"}`)
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "application/vnd.other+json")
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusUnsupportedMediaType, pe.Status)
+}
+
 func TestMissingContentType(t *testing.T) {
 	r, err := http.NewRequest(http.MethodPost, "/", nil)
 	assert.NoError(t, err)
@@ -507,6 +1394,99 @@ func TestMissingContentType(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestDefaultContentType(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.DefaultContentType = "application/json"
+
+	r, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(`{"field1": "value1"}`))
+	assert.NoError(t, err)
+	results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}}, results)
+
+	// a Content-Type sent by the client is kept
+	r, err = constructURLEncodedForm(url.Values{"field1": {"value1"}})
+	assert.NoError(t, err)
+	results, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}}, results)
+
+	cfg.DefaultContentType = "application/x-www-form-urlencoded"
+	r, err = http.NewRequest(http.MethodPost, "/", strings.NewReader("field1=value1"))
+	assert.NoError(t, err)
+	results, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}}, results)
+}
+
+func TestSniffContentType(t *testing.T) {
+	var sniffTests = []struct {
+		testName             string
+		defaultContentType   string
+		body                 string
+		expectedValuesOutput map[string][]string
+		expectedStatus       int
+	}{
+		{"JSON object", "", " \n {\"field1\": \"value1\"}", map[string][]string{"field1": {"value1"}}, 0},
+		{"URL encoded", "", "field1=value1&field2=value2", map[string][]string{"field1": {"value1"}, "field2": {"value2"}}, 0},
+		{"JSON array is JSON", "", `["value1"]`, nil, http.StatusBadRequest},
+		{"whitespace only", "", " \r\n", nil, http.StatusUnsupportedMediaType},
+		{"binary", "", "\x00\x01", nil, http.StatusUnsupportedMediaType},
+		{"inconclusive falls back to default", "application/json", "", nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range sniffTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.SniffContentType = true
+			cfg.DefaultContentType = tt.defaultContentType
+
+			r, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			assert.NoError(t, err)
+			results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+			if tt.expectedStatus != 0 {
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, tt.expectedStatus, pe.Status)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestContentType(t *testing.T) {
+	var contentTypeTests = []struct {
+		header              string
+		expectedContentType string
+		expectedError       bool
+	}{
+		{"application/json", "application/json", false},
+		{"application/x-www-form-urlencoded", "application/x-www-form-urlencoded", false},
+		{"multipart/form-data; boundary=abc123", "multipart/form-data", false},
+		{"application/fake-test-content-type", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range contentTypeTests {
+		r, err := http.NewRequest(http.MethodPost, "/", nil)
+		assert.NoError(t, err)
+		r.Header.Set("Content-Type", tt.header)
+
+		contentType, err := ContentType(r)
+		assert.Equal(t, tt.expectedContentType, contentType)
+		if tt.expectedError {
+			var pe *ParseError
+			assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+			assert.Equal(t, http.StatusUnsupportedMediaType, pe.Status)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
 func constructJSONEncodedForm(jsonStr string) (*http.Request, error) {
 	r, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(jsonStr))
 	r.Header.Set("Content-Type", "application/json")
@@ -571,6 +1551,43 @@ func constructMultipartForm(values map[string]io.Reader) (r *http.Request, err e
 	return req, err
 }
 
+// multipartPart is a single part of a multipart form, a file part when fileName is set
+type multipartPart struct {
+	field    string
+	fileName string
+	value    string
+}
+
+// constructMultipartFormParts builds a multipart form with the parts in the given order,
+// allowing the same field name to be used by multiple parts
+func constructMultipartFormParts(parts []multipartPart) (*http.Request, error) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	for _, part := range parts {
+		var fw io.Writer
+		var err error
+		if part.fileName != "" {
+			fw, err = w.CreateFormFile(part.field, part.fileName)
+		} else {
+			fw, err = w.CreateFormField(part.field)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(fw, part.value); err != nil {
+			return nil, err
+		}
+	}
+	w.Close()
+
+	req, err := http.NewRequest(http.MethodPost, "/", &b)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req, nil
+}
+
 // generates a 1_091_902 byte valid json string
 func generateBigJSON() string {
 	var sb strings.Builder
@@ -584,3 +1601,104 @@ func generateBigJSON() string {
 	bigJSON := sb.String()
 	return bigJSON
 }
+
+// cancellingReader cancels a context once more than limit bytes have been read through it
+type cancellingReader struct {
+	io.Reader
+	limit  int
+	read   int
+	cancel context.CancelFunc
+}
+
+func (cr *cancellingReader) Read(p []byte) (int, error) {
+	if len(p) > 1024 {
+		p = p[:1024]
+	}
+	n, err := cr.Reader.Read(p)
+	if cr.read += n; cr.read > cr.limit {
+		cr.cancel()
+	}
+	return n, err
+}
+
+func TestGetFormContent_MultipartCancelled(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "file1", fileName: "one.txt", value: strings.Repeat("a", 64*1024)},
+		{field: "file2", fileName: "two.txt", value: strings.Repeat("b", 64*1024)},
+	})
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	r = r.WithContext(ctx)
+	// cancel once the first file has been partly written to disk
+	r.Body = ioutil.NopCloser(&cancellingReader{Reader: r.Body, limit: 32 * 1024, cancel: cancel})
+
+	cfg := Config{MaxFormSize: megabyte, MaxFormWithFilesSize: megabyte, MaxMemory: 1024}
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusRequestTimeout, pe.Status)
+		assert.True(t, errors.Is(err, context.Canceled))
+	}
+
+	tempFiles, err := ioutil.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Empty(t, tempFiles, "temp files should be removed")
+}
+
+func BenchmarkGetFormContent_EmptyURLEncoded(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(""))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if _, _, err := GetFormContent(httptest.NewRecorder(), r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetFormContent_TinyURLEncoded(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("field1=value1"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if _, _, err := GetFormContent(httptest.NewRecorder(), r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseMapInterface_Empty(b *testing.B) {
+	b.ReportAllocs()
+	empty := map[string]interface{}{}
+	for i := 0; i < b.N; i++ {
+		ParseMapInterface(empty)
+	}
+}
+
+func BenchmarkReduceUnansweredFields(b *testing.B) {
+	// 10,000 fields, every other one unanswered
+	fields := make(map[string][]string, 10000)
+	for i := 0; i < 10000; i++ {
+		if i%2 == 0 {
+			fields[fmt.Sprintf("field%d", i)] = []string{""}
+		} else {
+			fields[fmt.Sprintf("field%d", i)] = []string{"value"}
+		}
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		results := make(map[string][]string, len(fields))
+		for field, values := range fields {
+			results[field] = values
+		}
+		b.StartTimer()
+
+		reduceUnansweredFields(results)
+	}
+}
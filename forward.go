@@ -0,0 +1,116 @@
+package formhandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+)
+
+// ForwardError is returned by Forward when the form couldn't be sent downstream, so forwarding
+// failures can be told apart from a *ParseError returned while parsing the request
+type ForwardError struct {
+	Err error
+}
+
+func (fe *ForwardError) Error() string {
+	return "formhandler: forwarding form: " + fe.Err.Error()
+}
+
+func (fe *ForwardError) Unwrap() error {
+	return fe.Err
+}
+
+// Forward POSTs parsed results and files to the ForwardTo URL, returning the status of the
+// downstream response. Forms with files are sent as multipart/form-data, the files streamed from
+// their temporary files rather than loaded into memory, and forms without files as a JSON object
+// in the shape of ToMapInterface. Any failure to send the form is returned as a *ForwardError,
+// while a downstream error status is returned with a nil error.
+func (cfg Config) Forward(ctx context.Context, results map[string][]string, files map[string][]*multipart.FileHeader) (status int, err error) {
+	if cfg.ForwardTo == nil {
+		return 0, &ForwardError{Err: errors.New("Config.ForwardTo is not set")}
+	}
+
+	var body io.Reader
+	var contentType string
+	if len(files) > 0 {
+		pr, pw := io.Pipe()
+		mw := multipart.NewWriter(pw)
+		go func() {
+			pw.CloseWithError(writeMultipartForm(mw, results, files))
+		}()
+		// stops the writer if the request fails before the body is read
+		defer pr.Close()
+		body, contentType = pr, mw.FormDataContentType()
+	} else {
+		encoded, encodeErr := json.Marshal(ToMapInterface(results))
+		if encodeErr != nil {
+			return 0, &ForwardError{Err: encodeErr}
+		}
+		body, contentType = bytes.NewReader(encoded), headerValApplicationJSON
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.ForwardTo.String(), body)
+	if err != nil {
+		return 0, &ForwardError{Err: err}
+	}
+	req.Header.Set(headerKeyContentType, contentType)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, &ForwardError{Err: err}
+	}
+	defer resp.Body.Close()
+	// drained so the connection can be reused
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// writeMultipartForm writes the results and files as a multipart form in alphabetical order of
+// field, each file keeping the Content-Type of the part it was uploaded in
+func writeMultipartForm(mw *multipart.Writer, results map[string][]string, files map[string][]*multipart.FileHeader) error {
+	for _, field := range sortedFields(results) {
+		for _, value := range results[field] {
+			if err := mw.WriteField(field, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, field := range sortedFields(files) {
+		for _, header := range files[field] {
+			partHeader := make(textproto.MIMEHeader)
+			partHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(field), escapeQuotes(header.Filename)))
+			contentType := header.Header.Get(headerKeyContentType)
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+			partHeader.Set(headerKeyContentType, contentType)
+
+			if err := copyFilePart(mw, partHeader, header); err != nil {
+				return err
+			}
+		}
+	}
+
+	return mw.Close()
+}
+
+func copyFilePart(mw *multipart.Writer, partHeader textproto.MIMEHeader, header *multipart.FileHeader) error {
+	part, err := mw.CreatePart(partHeader)
+	if err != nil {
+		return err
+	}
+	f, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(part, f)
+	return err
+}
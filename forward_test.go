@@ -0,0 +1,85 @@
+package formhandler
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForward(t *testing.T) {
+	var received *Form
+	var receivedContentType string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedContentType, _ = ContentType(r)
+		var err error
+		received, err = GetForm(w, r)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer downstream.Close()
+
+	downstreamURL, err := url.Parse(downstream.URL)
+	assert.NoError(t, err)
+	cfg := defaultConfig()
+	cfg.ForwardTo = downstreamURL
+
+	t.Run("form without files", func(t *testing.T) {
+		results := map[string][]string{"field1": {"value1"}, "field2": {"a", "b"}}
+		status, err := cfg.Forward(context.Background(), results, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, status)
+		assert.Equal(t, headerValApplicationJSON, receivedContentType)
+		assert.Equal(t, results, received.Values)
+	})
+
+	t.Run("form with files", func(t *testing.T) {
+		r, err := constructMultipartFormParts([]multipartPart{
+			{field: "field1", value: "value1"},
+			{field: "file1", fileName: `say "hi".txt`, value: "file content"},
+		})
+		assert.NoError(t, err)
+		results, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+		assert.NoError(t, err)
+
+		status, err := cfg.Forward(context.Background(), results, files)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, status)
+		assert.Equal(t, headerValFormMultipart, receivedContentType)
+		assert.Equal(t, results, received.Values)
+		if assert.Len(t, received.Files["file1"], 1) {
+			assert.Equal(t, `say "hi".txt`, received.Files["file1"][0].Filename)
+			f, err := received.Files["file1"][0].Open()
+			assert.NoError(t, err)
+			content, err := ioutil.ReadAll(f)
+			assert.NoError(t, err)
+			assert.Equal(t, "file content", string(content))
+		}
+	})
+
+	t.Run("downstream error status", func(t *testing.T) {
+		status, err := cfg.Forward(context.Background(), map[string][]string{"field1": {}}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("forwarding failures", func(t *testing.T) {
+		unreachable := defaultConfig()
+		unreachable.ForwardTo = &url.URL{Scheme: "http", Host: "127.0.0.1:0"}
+		for _, cfg := range []Config{defaultConfig(), unreachable} {
+			_, err := cfg.Forward(context.Background(), map[string][]string{"field1": {"value1"}}, nil)
+			var fe *ForwardError
+			assert.True(t, errors.As(err, &fe), "Returned error is not base type ForwardError")
+			var pe *ParseError
+			assert.False(t, errors.As(err, &pe))
+		}
+	})
+}
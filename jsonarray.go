@@ -0,0 +1,48 @@
+package formhandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ParseJSONArray parses a JSON array of flat objects, each following the same rules as a JSON
+// form, returning the results of every object in order. Arrays that are empty or contain
+// anything other than objects are rejected with a 400, as are objects breaking the value rules,
+// with the index of the object in the message. The reader is not size limited, so wrap it with
+// http.MaxBytesReader when reading a request body.
+func ParseJSONArray(reader io.Reader) (batch []map[string][]string, err *ParseError) {
+	return defaultConfig().parseJSONArray(reader)
+}
+
+func (cfg Config) parseJSONArray(reader io.Reader) (batch []map[string][]string, err *ParseError) {
+	dec := json.NewDecoder(reader)
+	var jsonContent []interface{}
+	if decodeErr := dec.Decode(&jsonContent); decodeErr != nil {
+		return nil, jsonDecodeError(decodeErr)
+	}
+
+	if secondDecodeErr := dec.Decode(&struct{}{}); secondDecodeErr != io.EOF {
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Request body must only contain a single JSON array"}
+	}
+
+	if len(jsonContent) == 0 {
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Request body must contain at least one object"}
+	}
+
+	batch = make([]map[string][]string, 0, len(jsonContent))
+	for i, element := range jsonContent {
+		object, ok := element.(map[string]interface{})
+		if !ok {
+			return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Element %d: Request body array must only contain JSON objects", i)}
+		}
+
+		results, parseErr := cfg.ParseMapInterface(object)
+		if parseErr != nil {
+			return nil, &ParseError{Status: parseErr.Status, Msg: fmt.Sprintf("Element %d: %s", i, parseErr.Msg)}
+		}
+		batch = append(batch, results)
+	}
+	return batch, nil
+}
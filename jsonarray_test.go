@@ -0,0 +1,47 @@
+package formhandler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJSONArray(t *testing.T) {
+	var jsonArrayTests = []struct {
+		testName       string
+		body           string
+		expectedBatch  []map[string][]string
+		expectedStatus int
+		expectedMsg    string
+	}{
+		{
+			"multiple objects",
+			`[{"field1":"value1"},{"field1":"value2","field2":["a","b"]}]`,
+			[]map[string][]string{{"field1": {"value1"}}, {"field1": {"value2"}, "field2": {"a", "b"}}},
+			0, "",
+		},
+		{"empty array", `[]`, nil, http.StatusBadRequest, "Request body must contain at least one object"},
+		{"element not an object", `[{"field1":"value1"},"value2"]`, nil, http.StatusBadRequest, "Element 1: Request body array must only contain JSON objects"},
+		{"invalid element value", `[{"field1":1}]`, nil, http.StatusBadRequest, `Element 0: JSON object contains invalid value for field "field1", values must be string or []string types`},
+		{"empty element", `[{"field1":"value1"},{}]`, nil, http.StatusBadRequest, "Element 1: JSON object contains no fields"},
+		{"single object", `{"field1":"value1"}`, nil, http.StatusBadRequest, "Request body contains an invalid JSON object (at position 1)"},
+		{"multiple arrays", `[{"a":"a"}][{"b":"b"}]`, nil, http.StatusBadRequest, "Request body must only contain a single JSON array"},
+		{"malformed", `[{"field1":`, nil, http.StatusBadRequest, "Request body contains badly-formed JSON"},
+		{"empty body", ``, nil, http.StatusBadRequest, "Request body must not be empty"},
+	}
+
+	for _, tt := range jsonArrayTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			batch, pe := ParseJSONArray(strings.NewReader(tt.body))
+			assert.Equal(t, tt.expectedBatch, batch)
+			if tt.expectedStatus == 0 {
+				assert.Nil(t, pe)
+			} else if assert.NotNil(t, pe) {
+				assert.Equal(t, tt.expectedStatus, pe.Status)
+				assert.Equal(t, tt.expectedMsg, pe.Msg)
+			}
+		})
+	}
+}
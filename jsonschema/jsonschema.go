@@ -0,0 +1,82 @@
+// Package jsonschema validates application/json request bodies parsed by formhandler against a
+// JSON Schema document before they are flattened into form values:
+//
+//	var parser = formhandler.New(jsonschema.WithJSONSchema(signupSchema))
+//
+// Bodies that don't match the schema are rejected with a 400 listing every violation, other
+// Content-Types aren't validated.
+//
+// WithJSONSchema used to be part of the formhandler package, as formhandler.WithJSONSchema, and
+// moved here so the core package doesn't depend on a schema library.
+package jsonschema
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/charlesworth/formhandler"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// schemaURL is the URL the schema given to WithJSONSchema is compiled under, relative references
+// within it are resolved against it
+const schemaURL = "formhandler-schema.json"
+
+// WithJSONSchema sets the JSONValidator of a Parser to validate application/json request bodies
+// against the JSON Schema document schema. It panics if schema isn't a valid JSON Schema.
+func WithJSONSchema(schema []byte) formhandler.Option {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(schemaURL, bytes.NewReader(schema)); err != nil {
+		panic(fmt.Sprintf("formhandler/jsonschema: WithJSONSchema given invalid schema: %v", err))
+	}
+	compiled, err := compiler.Compile(schemaURL)
+	if err != nil {
+		panic(fmt.Sprintf("formhandler/jsonschema: WithJSONSchema given invalid schema: %v", err))
+	}
+
+	return func(cfg *formhandler.Config) {
+		cfg.JSONValidator = func(object map[string]interface{}) error {
+			if parseErr := validate(compiled, object); parseErr != nil {
+				return parseErr
+			}
+			return nil
+		}
+	}
+}
+
+// validate checks a decoded JSON document against schema, returning a 400 listing
+// the location and message of every violation in order of location
+func validate(schema *jsonschema.Schema, document interface{}) *formhandler.ParseError {
+	err := schema.Validate(document)
+	if err == nil {
+		return nil
+	}
+
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return &formhandler.ParseError{Status: http.StatusBadRequest, Msg: "JSON object could not be validated against the schema", Err: err}
+	}
+
+	var violations []string
+	var collect func(ve *jsonschema.ValidationError)
+	collect = func(ve *jsonschema.ValidationError) {
+		if len(ve.Causes) == 0 {
+			location := ve.InstanceLocation
+			if location == "" {
+				location = "/"
+			}
+			violations = append(violations, fmt.Sprintf("%s: %s", location, ve.Message))
+			return
+		}
+		for _, cause := range ve.Causes {
+			collect(cause)
+		}
+	}
+	collect(validationErr)
+	sort.Strings(violations)
+
+	return &formhandler.ParseError{Status: http.StatusBadRequest, Msg: "JSON object does not match the schema: " + strings.Join(violations, "; ")}
+}
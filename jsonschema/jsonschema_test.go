@@ -0,0 +1,69 @@
+package jsonschema
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/charlesworth/formhandler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithJSONSchema(t *testing.T) {
+	parser := formhandler.New(WithJSONSchema([]byte(`{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": "string", "minLength": 2},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2}
+		}
+	}`)))
+
+	var schemaTests = []struct {
+		testName             string
+		body                 string
+		expectedValuesOutput map[string][]string
+		expectedMsg          string
+	}{
+		{"valid", `{"name": "charlie", "tags": ["a", "b"]}`, map[string][]string{"name": {"charlie"}, "tags": {"a", "b"}}, ""},
+		{"missing property", `{"tags": ["a"]}`, nil, "JSON object does not match the schema: /: missing properties: 'name'"},
+		{
+			"several violations",
+			`{"name": "c", "tags": ["a", "b", "c"]}`,
+			nil,
+			"JSON object does not match the schema: /name: length must be >= 2, but got 1; /tags: maximum 2 items required, but found 3 items",
+		},
+	}
+
+	for _, tt := range schemaTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+
+			results, _, err := parser.Parse(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+			if tt.expectedMsg != "" {
+				var pe *formhandler.ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, http.StatusBadRequest, pe.Status)
+					assert.Equal(t, tt.expectedMsg, pe.Msg)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	// other Content-Types aren't validated
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{"tags": {"a", "b", "c"}}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	results, _, err := parser.Parse(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"tags": {"a", "b", "c"}}, results)
+
+	assert.Panics(t, func() { WithJSONSchema([]byte(`{"type": 1}`)) })
+	assert.Panics(t, func() { WithJSONSchema([]byte(`not json`)) })
+}
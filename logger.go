@@ -0,0 +1,15 @@
+package formhandler
+
+// Logger receives the events logged by the Middleware, such as rejected requests and temporary
+// files that couldn't be removed. *log.Logger implements it, and adapters can route the events to
+// a structured logger or a test. The parsing functions themselves never log.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// logf logs to the Config's Logger, if it has one
+func (cfg Config) logf(format string, v ...interface{}) {
+	if cfg.Logger != nil {
+		cfg.Logger.Printf(format, v...)
+	}
+}
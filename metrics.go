@@ -0,0 +1,34 @@
+package formhandler
+
+import "time"
+
+// Metrics observes every form parsed with a Config, for exporting parse latency, body sizes and
+// error rates to a monitoring system such as Prometheus. Implementations must be safe to call
+// from multiple goroutines.
+type Metrics interface {
+	// ObserveParse is called once parsing a request finishes with the request's content type as
+	// it is used to choose a parser, e.g. "multipart/form-data" without a boundary, the number
+	// of body bytes read, how long parsing took and the error returned, if any. Errors from
+	// parsing the request are a *ParseError holding the response status. The content type is
+	// one of a fixed set so it can be used as a metric label: a content type with a parser,
+	// "missing" when the request has none or "unsupported" for any other.
+	ObserveParse(contentType string, bytes int64, dur time.Duration, err error)
+}
+
+const (
+	metricsContentTypeMissing     = "missing"
+	metricsContentTypeUnsupported = "unsupported"
+)
+
+// metricsContentType returns the content type observed by Metrics, bounding the values a
+// client can cause to be observed
+func metricsContentType(contentType string) string {
+	switch {
+	case contentType == "":
+		return metricsContentTypeMissing
+	case contentType == headerValTextPlain || isSupportedContentType(contentType):
+		return contentType
+	default:
+		return metricsContentTypeUnsupported
+	}
+}
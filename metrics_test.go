@@ -0,0 +1,86 @@
+package formhandler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type parseObservation struct {
+	contentType string
+	bytes       int64
+	dur         time.Duration
+	err         error
+}
+
+type recordingMetrics struct {
+	mu           sync.Mutex
+	observations []parseObservation
+}
+
+func (rm *recordingMetrics) ObserveParse(contentType string, bytes int64, dur time.Duration, err error) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.observations = append(rm.observations, parseObservation{contentType, bytes, dur, err})
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := &recordingMetrics{}
+	cfg := defaultConfig()
+	cfg.Metrics = metrics
+
+	body := url.Values{"field1": {"value1"}}.Encode()
+	r, err := constructURLEncodedForm(url.Values{"field1": {"value1"}})
+	assert.NoError(t, err)
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+
+	r, err = constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "one.txt", value: "one"}})
+	assert.NoError(t, err)
+	multipartSize := r.ContentLength
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+
+	r, err = constructJSONEncodedForm(`{"field1":1}`)
+	assert.NoError(t, err)
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.Error(t, err)
+
+	// content types without a parser are observed as one value, so clients can't add labels
+	r, err = constructJSONEncodedForm(`{"field1":"value1"}`)
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "application/x-random-1234")
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.Error(t, err)
+
+	r, err = constructJSONEncodedForm(`{"field1":"value1"}`)
+	assert.NoError(t, err)
+	r.Header.Del("Content-Type")
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.Error(t, err)
+
+	if assert.Len(t, metrics.observations, 5) {
+		assert.Equal(t, "application/x-www-form-urlencoded", metrics.observations[0].contentType)
+		assert.Equal(t, int64(len(body)), metrics.observations[0].bytes)
+		assert.NoError(t, metrics.observations[0].err)
+
+		assert.Equal(t, "multipart/form-data", metrics.observations[1].contentType)
+		assert.Equal(t, multipartSize, metrics.observations[1].bytes)
+		assert.NoError(t, metrics.observations[1].err)
+
+		assert.Equal(t, "application/json", metrics.observations[2].contentType)
+		var pe *ParseError
+		if assert.True(t, errors.As(metrics.observations[2].err, &pe), "Observed error is not base type ParseError") {
+			assert.Equal(t, http.StatusBadRequest, pe.Status)
+		}
+
+		assert.Equal(t, "unsupported", metrics.observations[3].contentType)
+		assert.Equal(t, "missing", metrics.observations[4].contentType)
+	}
+}
@@ -0,0 +1,72 @@
+package formhandler
+
+import (
+	"context"
+	"mime/multipart"
+	"net/http"
+)
+
+type contextKey string
+
+const (
+	// ResultsContextKey is the request context key the Middleware stores the form results under
+	ResultsContextKey = contextKey("formhandler.results")
+	// FilesContextKey is the request context key the Middleware stores the form files under
+	FilesContextKey = contextKey("formhandler.files")
+	// BodySizeContextKey is the request context key the Middleware stores the number of bytes
+	// read from the request body under
+	BodySizeContextKey = contextKey("formhandler.bodysize")
+)
+
+// Middleware parses the form content of every request the same as GetFormContent, storing
+// the results, files and body size in the request context for next to read with FormResults,
+// FormFiles and FormBodySize.
+// Requests that fail to parse are answered with WriteError and never reach next. Temporary
+// files are removed once next returns. Rejected requests and files that couldn't be removed
+// are logged to the Config's Logger.
+func Middleware(next http.Handler) http.Handler {
+	return NewMiddleware(defaultConfig())(next)
+}
+
+// NewMiddleware returns a Middleware parsing requests with the limits and options set in cfg
+func NewMiddleware(cfg Config) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			form, err := cfg.GetForm(w, r)
+			if err != nil {
+				cfg.logf("formhandler: %s %s rejected: %v", r.Method, r.URL.Path, err)
+				WriteError(w, err)
+				return
+			}
+			defer func() {
+				if err := form.Cleanup(); err != nil {
+					cfg.logf("formhandler: %s %s removing temporary files: %v", r.Method, r.URL.Path, err)
+				}
+			}()
+
+			ctx := context.WithValue(r.Context(), ResultsContextKey, form.Values)
+			ctx = context.WithValue(ctx, FilesContextKey, form.Files)
+			ctx = context.WithValue(ctx, BodySizeContextKey, form.BodySize)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// FormResults returns the form results stored in ctx by the Middleware
+func FormResults(ctx context.Context) map[string][]string {
+	results, _ := ctx.Value(ResultsContextKey).(map[string][]string)
+	return results
+}
+
+// FormFiles returns the form files stored in ctx by the Middleware
+func FormFiles(ctx context.Context) map[string][]*multipart.FileHeader {
+	files, _ := ctx.Value(FilesContextKey).(map[string][]*multipart.FileHeader)
+	return files
+}
+
+// FormBodySize returns the number of bytes read from the request body, stored in ctx by the
+// Middleware
+func FormBodySize(ctx context.Context) int64 {
+	size, _ := ctx.Value(BodySizeContextKey).(int64)
+	return size
+}
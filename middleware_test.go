@@ -0,0 +1,97 @@
+package formhandler
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	var results map[string][]string
+	var fileCount int
+	var bodySize int64
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		results = FormResults(r.Context())
+		fileCount = len(FormFiles(r.Context())["file1"])
+		bodySize = FormBodySize(r.Context())
+	}))
+
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "field1", value: "value1"},
+		{field: "file1", fileName: "a.txt", value: "a"},
+	})
+	assert.NoError(t, err)
+	contentLength := r.ContentLength
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}}, results)
+	assert.Equal(t, 1, fileCount)
+	assert.Equal(t, contentLength, bodySize)
+}
+
+func TestMiddleware_ParseError(t *testing.T) {
+	called := false
+	handler := NewMiddleware(defaultConfig())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	r, err := constructJSONEncodedForm(`{"field1": ""}`)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.False(t, called, "next handler should not be called for invalid forms")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMiddleware_Logger(t *testing.T) {
+	var logged bytes.Buffer
+	cfg := defaultConfig()
+	cfg.Logger = log.New(&logged, "", 0)
+	handler := NewMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r, err := constructJSONEncodedForm(`{"field1": "value1"}`)
+	assert.NoError(t, err)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Empty(t, logged.String())
+
+	r, err = constructJSONEncodedForm(`{"field1": ""}`)
+	assert.NoError(t, err)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "formhandler: POST / rejected: JSON object contains invalid value for field \"field1\", cannot use an empty string\n", logged.String())
+}
+
+func TestFormResults_NoMiddleware(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	assert.Nil(t, FormResults(r.Context()))
+	assert.Nil(t, FormFiles(r.Context()))
+}
+
+func TestMiddleware_Cleanup(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+
+	var tempFileCount int
+	handler := NewMiddleware(Config{MaxFormSize: megabyte, MaxFormWithFilesSize: megabyte, MaxMemory: 16})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tempFiles, err := ioutil.ReadDir(tempDir)
+		assert.NoError(t, err)
+		tempFileCount = len(tempFiles)
+	}))
+
+	r, err := constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "one.txt", value: strings.Repeat("a", 1024)}})
+	assert.NoError(t, err)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, 1, tempFileCount, "the file should be on disk while the handler runs")
+	tempFiles, err := ioutil.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Empty(t, tempFiles, "temp files should be removed after the handler returns")
+}
@@ -0,0 +1,43 @@
+package formhandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// mergeJSONMultipartFields replaces each value of the JSONMultipartFields with the fields of the
+// JSON object it holds, following the JSON value rules. The object's fields are namespaced under
+// the multipart field name, e.g. "metadata.title", unless FlattenJSONMultipartFields is set.
+func (cfg Config) mergeJSONMultipartFields(results map[string][]string) *ParseError {
+	for _, field := range cfg.JSONMultipartFields {
+		values, ok := results[field]
+		if !ok {
+			continue
+		}
+		delete(results, field)
+
+		for _, value := range values {
+			var jsonContent map[string]interface{}
+			if err := json.Unmarshal([]byte(value), &jsonContent); err != nil || jsonContent == nil {
+				return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Multipart field "%s" must contain a JSON object`, field)}
+			}
+
+			jsonResults, parseErr := cfg.flattenMap(jsonContent, fmt.Sprintf(`JSON object in multipart field "%s"`, field))
+			if parseErr != nil {
+				return parseErr
+			}
+
+			for key, jsonValues := range jsonResults {
+				if !cfg.FlattenJSONMultipartFields {
+					key = field + "." + key
+				}
+				if _, exists := results[key]; exists {
+					return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Field "%s" of the JSON object in multipart field "%s" is already set`, key, field)}
+				}
+				results[key] = jsonValues
+			}
+		}
+	}
+	return nil
+}
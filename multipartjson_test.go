@@ -0,0 +1,54 @@
+package formhandler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONMultipartFields(t *testing.T) {
+	var jsonMultipartTests = []struct {
+		testName             string
+		flatten              bool
+		metadata             string
+		expectedValuesOutput map[string][]string
+		expectedError        bool
+	}{
+		{"namespaced", false, `{"title": "holiday", "tags": ["beach", "sun"]}`, map[string][]string{"name": {"charlie"}, "metadata.title": {"holiday"}, "metadata.tags": {"beach", "sun"}}, false},
+		{"flattened", true, `{"title": "holiday"}`, map[string][]string{"name": {"charlie"}, "title": {"holiday"}}, false},
+		{"flattened conflicting field", true, `{"name": "sam"}`, nil, true},
+		{"invalid JSON", false, `{"title": `, nil, true},
+		{"JSON array", false, `["holiday"]`, nil, true},
+		{"nested object", false, `{"title": {"text": "holiday"}}`, nil, true},
+	}
+
+	for _, tt := range jsonMultipartTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.JSONMultipartFields = []string{"metadata"}
+			cfg.FlattenJSONMultipartFields = tt.flatten
+
+			r, err := constructMultipartFormParts([]multipartPart{
+				{field: "name", value: "charlie"},
+				{field: "metadata", value: tt.metadata},
+				{field: "photo", fileName: "photo.jpg", value: "photo"},
+			})
+			assert.NoError(t, err)
+
+			results, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+			if tt.expectedError {
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, http.StatusBadRequest, pe.Status)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, files["photo"], 1)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package formhandler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const headerValApplicationNDJSON = "application/x-ndjson"
+
+// GetFormBatch parses an application/x-ndjson request, where each line is a separate flat JSON
+// object following the same rules as a JSON form, returning the results of every line in order.
+// Blank lines are skipped. Errors on a line are rejected with the status a JSON form would have
+// and the line number in the message, other Content-Types are rejected with a 415.
+func GetFormBatch(w http.ResponseWriter, r *http.Request) (batch []map[string][]string, err error) {
+	return defaultConfig().GetFormBatch(w, r)
+}
+
+// GetFormBatch operates the same as the package level GetFormBatch, using the limits and
+// options set on the Config
+func (cfg Config) GetFormBatch(w http.ResponseWriter, r *http.Request) (batch []map[string][]string, err error) {
+	cfg = cfg.withDefaults()
+	if contentType := cfg.getContentType(r); contentType != headerValApplicationNDJSON {
+		if contentType == "" {
+			return nil, missingContentTypeError()
+		}
+		return nil, unsupportedContentTypeError(contentType)
+	}
+	if r.Body == nil {
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Request body must not be empty"}
+	}
+
+	batch, parseErr := cfg.parseNDJSON(http.MaxBytesReader(w, r.Body, cfg.MaxFormSize))
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return batch, nil
+}
+
+func (cfg Config) parseNDJSON(reader io.Reader) (batch []map[string][]string, err *ParseError) {
+	lines := bufio.NewReader(reader)
+	for lineNumber := 1; ; lineNumber++ {
+		line, readErr := lines.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			if isRequestTooLarge(readErr) {
+				return nil, &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}
+			}
+			return nil, &ParseError{Status: http.StatusInternalServerError, Msg: "Request body read error"}
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			results, parseErr := cfg.parseNDJSONLine(line)
+			if parseErr != nil {
+				return nil, &ParseError{Status: parseErr.Status, Msg: fmt.Sprintf("Line %d: %s", lineNumber, parseErr.Msg)}
+			}
+			batch = append(batch, results)
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if len(batch) == 0 {
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Request body must not be empty"}
+	}
+	return batch, nil
+}
+
+func (cfg Config) parseNDJSONLine(line []byte) (results map[string][]string, err *ParseError) {
+	jsonContent := map[string]interface{}{}
+	if unmarshalErr := json.Unmarshal(line, &jsonContent); unmarshalErr != nil {
+		var unmarshalTypeError *json.UnmarshalTypeError
+		if errors.As(unmarshalErr, &unmarshalTypeError) {
+			return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Line must contain a JSON object"}
+		}
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Line contains badly-formed JSON"}
+	}
+	return cfg.ParseMapInterface(jsonContent)
+}
@@ -0,0 +1,79 @@
+package formhandler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFormBatch(t *testing.T) {
+	var batchTests = []struct {
+		testName       string
+		body           string
+		expectedBatch  []map[string][]string
+		expectedStatus int
+		expectedLine   string
+	}{
+		{
+			"multiple records",
+			"{\"field1\":\"value1\"}\n{\"field1\":\"value2\",\"field2\":[\"a\",\"b\"]}\n",
+			[]map[string][]string{{"field1": {"value1"}}, {"field1": {"value2"}, "field2": {"a", "b"}}},
+			0, "",
+		},
+		{
+			"blank lines and no trailing newline",
+			"\n{\"field1\":\"value1\"}\r\n\n{\"field1\":\"value2\"}",
+			[]map[string][]string{{"field1": {"value1"}}, {"field1": {"value2"}}},
+			0, "",
+		},
+		{"malformed line", "{\"field1\":\"value1\"}\n{\"field1\":\n", nil, http.StatusBadRequest, "Line 2: "},
+		{"invalid value", "{\"field1\":\"value1\"}\n\n{\"field1\":1}\n", nil, http.StatusBadRequest, "Line 3: "},
+		{"not an object", "[\"value1\"]\n", nil, http.StatusBadRequest, "Line 1: "},
+		{"empty body", "\n\n", nil, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range batchTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			assert.NoError(t, err)
+			r.Header.Set("Content-Type", "application/x-ndjson")
+
+			batch, err := GetFormBatch(httptest.NewRecorder(), r)
+			if tt.expectedStatus != 0 {
+				assert.Nil(t, batch)
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, tt.expectedStatus, pe.Status)
+					assert.True(t, strings.HasPrefix(pe.Msg, tt.expectedLine), pe.Msg)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedBatch, batch)
+			}
+		})
+	}
+}
+
+func TestGetFormBatch_Limits(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.MaxFormSize = 32
+
+	r, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("{\"field1\":\"value1\"}\n", 3)))
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "application/x-ndjson")
+	_, err = cfg.GetFormBatch(httptest.NewRecorder(), r)
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, pe.Status)
+
+	r, err = constructURLEncodedForm(url.Values{"field1": {"value1"}})
+	assert.NoError(t, err)
+	_, err = cfg.GetFormBatch(httptest.NewRecorder(), r)
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusUnsupportedMediaType, pe.Status)
+}
@@ -0,0 +1,227 @@
+package formhandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sort"
+)
+
+// OrderedFile is an uploaded file and the form field it was uploaded under
+type OrderedFile struct {
+	Field  string
+	Header *multipart.FileHeader
+}
+
+// OrderedFiles returns every file in the form as a slice. Iterating the Files map doesn't give
+// a stable order, so when the form was parsed with RecordFileOrder the files are returned in the
+// order they appeared in the multipart body. Otherwise, and for files that weren't multipart file
+// parts such as decoded data URIs, files are ordered by field name and then upload order.
+func (f *Form) OrderedFiles() []OrderedFile {
+	ordered := make([]OrderedFile, 0, len(f.Files))
+	taken := make(map[string]int, len(f.Files))
+
+	for _, field := range f.fileOrder {
+		if i := taken[field]; i < len(f.Files[field]) {
+			ordered = append(ordered, OrderedFile{Field: field, Header: f.Files[field][i]})
+			taken[field]++
+		}
+	}
+
+	fields := make([]string, 0, len(f.Files))
+	for field := range f.Files {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		for _, header := range f.Files[field][taken[field]:] {
+			ordered = append(ordered, OrderedFile{Field: field, Header: header})
+		}
+	}
+
+	return ordered
+}
+
+// fileOrderRecorder records the field names of multipart file parts in the order they are read
+// from the request body. ParseMultipartForm groups files by field, losing the order between
+// fields, so the body is copied to a second multipart reader as it is parsed. Files within a
+// field keep their order, so the nth recorded part of a field is the nth file of that field.
+type fileOrderRecorder struct {
+	pw     *io.PipeWriter
+	done   chan struct{}
+	fields []string
+}
+
+// recordFileOrder starts recording the file part order of the multipart request body,
+// returning nil if the request has no multipart boundary
+func recordFileOrder(r *http.Request) *fileOrderRecorder {
+	_, params, err := mime.ParseMediaType(r.Header.Get(headerKeyContentType))
+	if err != nil || params["boundary"] == "" {
+		return nil
+	}
+
+	pr, pw := io.Pipe()
+	rec := &fileOrderRecorder{pw: pw, done: make(chan struct{})}
+	r.Body = &teeReadCloser{Reader: io.TeeReader(r.Body, pw), Closer: r.Body}
+
+	go func() {
+		defer close(rec.done)
+
+		mr := multipart.NewReader(pr, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			if part.FormName() != "" && part.FileName() != "" {
+				rec.fields = append(rec.fields, part.FormName())
+			}
+		}
+
+		// keep reading so writes to the pipe never block the parse
+		io.Copy(io.Discard, pr)
+	}()
+
+	return rec
+}
+
+// finish stops recording once the body has been parsed, returning the recorded field names
+func (rec *fileOrderRecorder) finish() []string {
+	if rec == nil {
+		return nil
+	}
+	rec.pw.Close()
+	<-rec.done
+	return rec.fields
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// renameOrderedFileKeys renames the file field names like renameKeys, but concatenates the
+// files of fields given the same name in the recorded body order
+func renameOrderedFileKeys(files map[string][]*multipart.FileHeader, order []string, rename func(key string) (string, bool)) (map[string][]*multipart.FileHeader, []string) {
+	renamed := make(map[string][]*multipart.FileHeader, len(files))
+	renamedOrder := make([]string, 0, len(order))
+	taken := make(map[string]int, len(files))
+
+	for _, field := range order {
+		if i := taken[field]; i < len(files[field]) {
+			if name, keep := rename(field); keep {
+				renamed[name] = append(renamed[name], files[field][i])
+				renamedOrder = append(renamedOrder, name)
+			}
+			taken[field]++
+		}
+	}
+
+	// files missing from the recorded order, which shouldn't occur, are kept at the end
+	rest := make(map[string][]*multipart.FileHeader, len(files))
+	for field, headers := range files {
+		if taken[field] < len(headers) {
+			rest[field] = headers[taken[field]:]
+		}
+	}
+	for field, headers := range renameKeys(rest, rename) {
+		renamed[field] = append(renamed[field], headers...)
+	}
+
+	return renamed, renamedOrder
+}
+
+// ParseOrdered operates the same as GetFormContent for JSON requests, additionally returning the
+// names of the fields in the results in the order they appear in the JSON object. It is only
+// supported for application/json requests, other Content-Types are rejected with a 415.
+func ParseOrdered(w http.ResponseWriter, r *http.Request) (results map[string][]string, fields []string, err error) {
+	return defaultConfig().ParseOrdered(w, r)
+}
+
+// ParseOrdered operates the same as the package level ParseOrdered, using the limits and
+// options set on the Config
+func (cfg Config) ParseOrdered(w http.ResponseWriter, r *http.Request) (results map[string][]string, fields []string, err error) {
+	cfg = cfg.withDefaults()
+	if contentType := cfg.getContentType(r); contentType != headerValApplicationJSON {
+		if contentType == "" {
+			return nil, nil, missingContentTypeError()
+		}
+		return nil, nil, unsupportedContentTypeError(contentType)
+	}
+
+	// the body is buffered so its keys can be read a second time, once the form is parsed. It's
+	// decompressed first, as the keys are read from the buffered body.
+	var body []byte
+	if r.Body != nil {
+		decompressed, parseErr := decompressBody(r)
+		if parseErr != nil {
+			return nil, nil, parseErr
+		}
+		body, err = ioutil.ReadAll(http.MaxBytesReader(w, r.Body, cfg.MaxFormSize))
+		if corruptErr := decompressed.corruptError(); corruptErr != nil {
+			return nil, nil, corruptErr
+		}
+		if err != nil {
+			if isRequestTooLarge(err) {
+				return nil, nil, &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}
+			}
+			return nil, nil, &ParseError{Status: http.StatusInternalServerError, Msg: "Request body read error"}
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		// the buffered body is no longer encoded, so it mustn't be decompressed a second time
+		r.Header.Del(headerKeyContentEncoding)
+	}
+
+	form, err := cfg.GetForm(w, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys, err := jsonKeyOrder(body)
+	if err != nil {
+		return nil, nil, &ParseError{Status: http.StatusInternalServerError, Msg: "JSON parsing error"}
+	}
+
+	// keys may repeat, be renamed into the same field, or be missing from the results when
+	// empty fields are omitted or unmapped fields dropped
+	seen := make(map[string]bool, len(keys))
+	fields = make([]string, 0, len(form.Values))
+	for _, key := range keys {
+		key, keep := cfg.resultKey(key)
+		if !keep {
+			continue
+		}
+		if _, ok := form.Values[key]; ok && !seen[key] {
+			seen[key] = true
+			fields = append(fields, key)
+		}
+	}
+	return form.Values, fields, nil
+}
+
+// jsonKeyOrder returns the keys of the JSON object in body in document order
+func jsonKeyOrder(body []byte) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, token.(string))
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
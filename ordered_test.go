@@ -0,0 +1,140 @@
+package formhandler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormOrderedFiles(t *testing.T) {
+	parts := []multipartPart{
+		{field: "photos", fileName: "b.png", value: "b"},
+		{field: "field1", value: "value1"},
+		{field: "avatar", fileName: "a.png", value: "a"},
+		{field: "photos", fileName: "c.png", value: "c"},
+		{field: "documents", fileName: "d.pdf", value: "d"},
+	}
+
+	orderedNames := func(ordered []OrderedFile) []string {
+		names := []string{}
+		for _, file := range ordered {
+			names = append(names, file.Field+"/"+file.Header.Filename)
+		}
+		return names
+	}
+
+	cfg := defaultConfig()
+	cfg.RecordFileOrder = true
+	r, err := constructMultipartFormParts(parts)
+	assert.NoError(t, err)
+	form, err := cfg.GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"photos/b.png", "avatar/a.png", "photos/c.png", "documents/d.pdf"}, orderedNames(form.OrderedFiles()))
+
+	// without recording, files are ordered by field name
+	r, err = constructMultipartFormParts(parts)
+	assert.NoError(t, err)
+	form, err = GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"avatar/a.png", "documents/d.pdf", "photos/b.png", "photos/c.png"}, orderedNames(form.OrderedFiles()))
+}
+
+func TestFormOrderedFiles_ParseError(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.RecordFileOrder = true
+	cfg.MaxFormWithFilesSize = 64
+
+	r, err := constructMultipartFormParts([]multipartPart{{field: "photos", fileName: "b.png", value: "more than 64 bytes of file content, which is over the limit"}})
+	assert.NoError(t, err)
+	form, err := cfg.GetForm(httptest.NewRecorder(), r)
+	assert.Nil(t, form)
+	assert.Error(t, err)
+}
+
+func TestFormOrderedFiles_CaseInsensitiveKeys(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.RecordFileOrder = true
+	cfg.CaseInsensitiveKeys = true
+
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "avatar", fileName: "one.png", value: "one"},
+		{field: "Avatar", fileName: "two.png", value: "two"},
+		{field: "avatar", fileName: "three.png", value: "three"},
+	})
+	assert.NoError(t, err)
+	form, err := cfg.GetForm(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+
+	var names []string
+	for _, file := range form.OrderedFiles() {
+		assert.Equal(t, "avatar", file.Field)
+		names = append(names, file.Header.Filename)
+	}
+	assert.Equal(t, []string{"one.png", "two.png", "three.png"}, names)
+	assert.Len(t, form.Files["avatar"], 3)
+}
+
+func TestParseOrdered(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.EmptyArrayStrategy = EmptyArrayOmit
+
+	var orderedTests = []struct {
+		testName       string
+		body           string
+		expectedFields []string
+	}{
+		{"document order", `{"zebra":"z","apple":["a","b"],"mango":"m"}`, []string{"zebra", "apple", "mango"}},
+		{"omitted field", `{"b":"b","empty":[],"a":"a"}`, []string{"b", "a"}},
+		{"repeated key", `{"b":"first","a":"a","b":"second"}`, []string{"b", "a"}},
+	}
+
+	for _, tt := range orderedTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructJSONEncodedForm(tt.body)
+			assert.NoError(t, err)
+
+			results, fields, err := cfg.ParseOrdered(httptest.NewRecorder(), r)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedFields, fields)
+			assert.Len(t, results, len(fields))
+		})
+	}
+
+	// keys are renamed the same as the results
+	cfg.CaseInsensitiveKeys = true
+	cfg.FieldRename = map[string]string{"b": "second", "a": "first"}
+	cfg.DropUnmapped = true
+	r, err := constructJSONEncodedForm(`{"B":"1","c":"3","A":"2"}`)
+	assert.NoError(t, err)
+	results, fields, err := cfg.ParseOrdered(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"second", "first"}, fields)
+	assert.Equal(t, map[string][]string{"second": {"1"}, "first": {"2"}}, results)
+
+	// compressed bodies are decompressed before their keys are read
+	r, err = constructJSONEncodedForm(`{"zebra":"z","apple":"a"}`)
+	assert.NoError(t, err)
+	results, fields, err = ParseOrdered(httptest.NewRecorder(), compressBody(t, r, "gzip"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"zebra", "apple"}, fields)
+	assert.Equal(t, map[string][]string{"zebra": {"z"}, "apple": {"a"}}, results)
+
+	// invalid JSON is reported the same as by GetFormContent
+	r, err = constructJSONEncodedForm(`{"field1":`)
+	assert.NoError(t, err)
+	_, _, err = ParseOrdered(httptest.NewRecorder(), r)
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusBadRequest, pe.Status)
+
+	// other content types aren't supported
+	r, err = constructURLEncodedForm(url.Values{"field1": {"value1"}})
+	assert.NoError(t, err)
+	_, _, err = ParseOrdered(httptest.NewRecorder(), r)
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusUnsupportedMediaType, pe.Status)
+}
@@ -0,0 +1,126 @@
+package formhandler
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/http"
+)
+
+// Parser parses form requests with options fixed when it's constructed by New, so handlers can
+// share a single Parser built at startup rather than configuring parsing on every request.
+type Parser struct {
+	cfg Config
+}
+
+// Option sets an option of a Parser constructed by New. Options given an invalid value, such as
+// a negative size, panic as a misconfigured Parser is a programming error.
+type Option func(cfg *Config)
+
+// New returns a Parser with the options applied in order, options not given keep the same
+// defaults as a zero Config
+func New(opts ...Option) *Parser {
+	var cfg Config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return NewParser(cfg)
+}
+
+// NewParser returns a Parser using the limits and options set in cfg, with size limits left at
+// zero set to their defaults
+func NewParser(cfg Config) *Parser {
+	return &Parser{cfg: cfg.withDefaults()}
+}
+
+// defaultParser is the Parser used by GetFormContent and GetForm
+var defaultParser = New()
+
+// Parse operates the same as GetFormContent, using the Parser's options
+func (p *Parser) Parse(w http.ResponseWriter, r *http.Request) (results map[string][]string, files map[string][]*multipart.FileHeader, err error) {
+	return p.cfg.GetFormContent(w, r)
+}
+
+// ParseForm operates the same as GetForm, using the Parser's options
+func (p *Parser) ParseForm(w http.ResponseWriter, r *http.Request) (*Form, error) {
+	return p.cfg.GetForm(w, r)
+}
+
+// Config returns a copy of the Parser's options, with the defaults applied
+func (p *Parser) Config() Config {
+	return p.cfg
+}
+
+// WithConfig replaces every option with those set in cfg, for options without a With function.
+// Options given after WithConfig are applied on top of cfg.
+func WithConfig(cfg Config) Option {
+	return func(c *Config) {
+		*c = cfg
+	}
+}
+
+// WithMaxFormSize sets the maximum size in bytes of a request without files, see
+// Config.MaxFormSize
+func WithMaxFormSize(n int64) Option {
+	mustNotBeNegative("WithMaxFormSize", n)
+	return func(cfg *Config) {
+		cfg.MaxFormSize = n
+	}
+}
+
+// WithMaxFormWithFilesSize sets the maximum size in bytes of a multipart/form-data request, see
+// Config.MaxFormWithFilesSize
+func WithMaxFormWithFilesSize(n int64) Option {
+	mustNotBeNegative("WithMaxFormWithFilesSize", n)
+	return func(cfg *Config) {
+		cfg.MaxFormWithFilesSize = n
+	}
+}
+
+// WithMaxMemory sets the number of bytes of multipart file parts stored in memory, see
+// Config.MaxMemory
+func WithMaxMemory(n int64) Option {
+	mustNotBeNegative("WithMaxMemory", n)
+	return func(cfg *Config) {
+		cfg.MaxMemory = n
+	}
+}
+
+// WithMaxFields sets the maximum number of fields a form may contain, see Config.MaxFields
+func WithMaxFields(n int) Option {
+	mustNotBeNegative("WithMaxFields", int64(n))
+	return func(cfg *Config) {
+		cfg.MaxFields = n
+	}
+}
+
+// WithMaxFiles sets the maximum number of files a multipart form may contain, see
+// Config.MaxFiles
+func WithMaxFiles(n int) Option {
+	mustNotBeNegative("WithMaxFiles", int64(n))
+	return func(cfg *Config) {
+		cfg.MaxFiles = n
+	}
+}
+
+// WithAllowedFileContentTypes sets the sniffed media types uploaded files may have, see
+// Config.AllowedFileContentTypes
+func WithAllowedFileContentTypes(contentTypes ...string) Option {
+	return func(cfg *Config) {
+		cfg.AllowedFileContentTypes = contentTypes
+	}
+}
+
+// WithAllowedMethods sets the HTTP methods forms may be submitted with, see
+// Config.AllowedMethods
+func WithAllowedMethods(methods ...string) Option {
+	return func(cfg *Config) {
+		cfg.AllowedMethods = methods
+	}
+}
+
+// mustNotBeNegative panics if n, the value given to the named option, is negative
+func mustNotBeNegative(option string, n int64) {
+	if n < 0 {
+		panic(fmt.Sprintf("formhandler: %s given negative value %d", option, n))
+	}
+}
@@ -0,0 +1,74 @@
+package formhandler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	assert.Equal(t, defaultConfig(), New().cfg)
+
+	p := New(
+		WithConfig(Config{MaxDecodedValueBytes: 8}),
+		WithMaxFormSize(64),
+		WithMaxFiles(1),
+		WithAllowedMethods(http.MethodPost, http.MethodDelete),
+	)
+	assert.Equal(t, int64(64), p.cfg.MaxFormSize)
+	assert.Equal(t, defaultConfig().MaxMemory, p.cfg.MaxMemory)
+	assert.Equal(t, 8, p.cfg.MaxDecodedValueBytes)
+	assert.Equal(t, 1, p.cfg.MaxFiles)
+
+	r, err := constructJSONEncodedForm(`{"field1": "value1"}`)
+	assert.NoError(t, err)
+	r.Method = http.MethodDelete
+	results, _, err := p.Parse(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}}, results)
+
+	r, err = constructMultipartFormParts([]multipartPart{
+		{field: "file1", fileName: "one.txt", value: "one"},
+		{field: "file2", fileName: "two.txt", value: "two"},
+	})
+	assert.NoError(t, err)
+	_, _, err = p.Parse(httptest.NewRecorder(), r)
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusBadRequest, pe.Status)
+	}
+}
+
+func TestNewParser(t *testing.T) {
+	p := NewParser(Config{MaxMemory: 16, WarnDroppedFields: true})
+	assert.Equal(t, defaultConfig().MaxFormSize, p.Config().MaxFormSize)
+	assert.Equal(t, int64(16), p.Config().MaxMemory)
+
+	// the Parser is reused across requests
+	for i := 0; i < 2; i++ {
+		r, err := constructMultipartFormParts([]multipartPart{
+			{field: "field1", value: "value1"},
+			{field: "field2", value: ""},
+			{field: "file1", fileName: "file.txt", value: "file content larger than MaxMemory"},
+		})
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		form, err := p.ParseForm(w, r)
+		if assert.NoError(t, err) {
+			assert.Equal(t, map[string][]string{"field1": {"value1"}}, form.Values)
+			assert.Len(t, form.Files["file1"], 1)
+			assert.NoError(t, form.Cleanup())
+		}
+		assert.Contains(t, w.Header().Get("Warning"), "field2")
+	}
+}
+
+func TestNew_InvalidOption(t *testing.T) {
+	assert.PanicsWithValue(t, "formhandler: WithMaxFormSize given negative value -1", func() {
+		New(WithMaxFormSize(-1))
+	})
+	assert.Panics(t, func() { WithMaxFiles(-1) })
+}
@@ -0,0 +1,99 @@
+package formhandler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// defaultMaxPartHeaderSize is the default Config.MaxPartHeaderSize
+const defaultMaxPartHeaderSize = 16 * 1024
+
+// errPartHeaderTooLarge is returned by a partHeaderLimiter once a part's header block is over
+// the limit, failing the multipart parse reading through it
+var errPartHeaderTooLarge = errors.New("multipart part header too large")
+
+// partHeaderLimiter caps the size of the header block of each part of a multipart body as it is
+// read. ParseMultipartForm buffers every header line of a part before the part's size counts
+// towards MaxMemory, so the body is scanned as it passes through: a line starting with the
+// boundary delimiter begins a header block, which ends at the first empty line.
+type partHeaderLimiter struct {
+	io.ReadCloser
+	delimiter []byte
+	max       int64
+
+	// matched is the number of delimiter bytes at the start of the current body line, or -1 once
+	// the line can't be a delimiter
+	matched int
+	// closing is set once the delimiter is followed by "--", ending the form
+	closing bool
+	// inHeader is set while reading a header block, headerBytes counting its size and lineBytes
+	// the size of its current line, excluding the line ending
+	inHeader    bool
+	headerBytes int64
+	lineBytes   int
+}
+
+// limitPartHeaders wraps the multipart request body in a partHeaderLimiter, returning nil if the
+// request has no multipart boundary
+func limitPartHeaders(r *http.Request, max int64) *partHeaderLimiter {
+	_, params, err := mime.ParseMediaType(r.Header.Get(headerKeyContentType))
+	if err != nil || params["boundary"] == "" {
+		return nil
+	}
+	limiter := &partHeaderLimiter{ReadCloser: r.Body, delimiter: []byte("--" + params["boundary"]), max: max}
+	r.Body = limiter
+	return limiter
+}
+
+// exceeded reports if the body was cut short by a part header over the limit
+func (l *partHeaderLimiter) exceeded() bool {
+	return l != nil && l.headerBytes > l.max
+}
+
+func (l *partHeaderLimiter) Read(p []byte) (int, error) {
+	n, err := l.ReadCloser.Read(p)
+	for _, b := range p[:n] {
+		if l.inHeader {
+			l.headerBytes++
+			if l.headerBytes > l.max {
+				return 0, errPartHeaderTooLarge
+			}
+			switch b {
+			case '\n':
+				l.inHeader = l.lineBytes > 0
+				l.lineBytes = 0
+			case '\r':
+			default:
+				l.lineBytes++
+			}
+			continue
+		}
+
+		switch {
+		case b == '\n':
+			// a delimiter line is followed by the next part's header block, unless it closes the form
+			if l.matched == len(l.delimiter) && !l.closing {
+				l.inHeader = true
+				l.headerBytes = 0
+			}
+			l.matched = 0
+			l.closing = false
+		case l.matched == len(l.delimiter):
+			if b == '-' {
+				l.closing = true
+			}
+		case l.matched >= 0 && b == l.delimiter[l.matched]:
+			l.matched++
+		default:
+			l.matched = -1
+		}
+	}
+	return n, err
+}
+
+func partHeaderTooLargeError(max int64) *ParseError {
+	return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Multipart form has a part with headers larger than %d bytes", max)}
+}
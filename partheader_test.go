@@ -0,0 +1,71 @@
+package formhandler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxPartHeaderSize(t *testing.T) {
+	const boundary = "testboundary"
+	part := func(name, extraHeaders string) string {
+		return fmt.Sprintf("--%s\r\nContent-Disposition: form-data; name=\"%s\"\r\n%s\r\nvalue\r\n", boundary, name, extraHeaders)
+	}
+	manyHeaders := strings.Repeat("X-Padding: aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\r\n", 100)
+
+	var partHeaderTests = []struct {
+		testName             string
+		body                 string
+		expectedValuesOutput map[string][]string
+		expectedStatus       int
+	}{
+		{
+			"small headers",
+			part("field1", "X-Padding: a\r\n") + part("field2", "") + "--" + boundary + "--\r\n",
+			map[string][]string{"field1": {"value"}, "field2": {"value"}},
+			0,
+		},
+		{
+			"large values",
+			fmt.Sprintf("--%s\r\nContent-Disposition: form-data; name=\"field1\"\r\n\r\n%s\r\n--%s--\r\n", boundary, strings.Repeat("v", 8192), boundary),
+			map[string][]string{"field1": {strings.Repeat("v", 8192)}},
+			0,
+		},
+		{
+			"large epilogue",
+			part("field1", "") + "--" + boundary + "--\r\n" + strings.Repeat("epilogue\r\n", 1000),
+			map[string][]string{"field1": {"value"}},
+			0,
+		},
+		{"large headers on first part", part("field1", manyHeaders) + "--" + boundary + "--\r\n", nil, http.StatusBadRequest},
+		{"large headers on later part", part("field1", "") + part("field2", manyHeaders) + "--" + boundary + "--\r\n", nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range partHeaderTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.MaxPartHeaderSize = 1024
+
+			r, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			assert.NoError(t, err)
+			r.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+
+			results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+			if tt.expectedStatus != 0 {
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, tt.expectedStatus, pe.Status)
+					assert.Equal(t, "Multipart form has a part with headers larger than 1024 bytes", pe.Msg)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
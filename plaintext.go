@@ -0,0 +1,38 @@
+package formhandler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const headerValTextPlain = "text/plain"
+
+// parseTextPlainForm parses the HTML text/plain form encoding, where each field is sent
+// unescaped as "name=value" on its own CRLF terminated line. The encoding is ambiguous when
+// values contain "=" or line breaks, so it's only used when enabled with ParsePlainTextForms.
+func parseTextPlainForm(r *http.Request) (results map[string][]string, err *ParseError) {
+	body, readErr := ioutil.ReadAll(r.Body)
+	if readErr != nil {
+		if isRequestTooLarge(readErr) {
+			return nil, &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}
+		}
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: "Invalid text/plain form"}
+	}
+
+	results = make(map[string][]string)
+	for i, line := range strings.Split(string(body), "\r\n") {
+		if line == "" {
+			continue
+		}
+
+		field, value, found := strings.Cut(line, "=")
+		if !found || field == "" {
+			return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Invalid text/plain form, line %d must be a name=value pair", i+1)}
+		}
+		results[field] = append(results[field], value)
+	}
+
+	return results, nil
+}
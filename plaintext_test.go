@@ -0,0 +1,77 @@
+package formhandler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFormContent_TextPlain(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.ParsePlainTextForms = true
+
+	var formContentTests = []struct {
+		testName             string
+		body                 string
+		expectedValuesOutput map[string][]string
+		expectedError        bool
+	}{
+		{
+			"single field",
+			"field1=value1\r\n",
+			map[string][]string{"field1": {"value1"}},
+			false,
+		},
+		{
+			"repeated and empty fields",
+			"field1=value 1\r\nfield2=\r\nfield1=a=b\r\n",
+			map[string][]string{"field1": {"value 1", "a=b"}},
+			false,
+		},
+		{
+			"missing final line break",
+			"field1=value1",
+			map[string][]string{"field1": {"value1"}},
+			false,
+		},
+		{
+			"line without separator",
+			"field1=value1\r\nfield2\r\n",
+			nil,
+			true,
+		},
+		{
+			"empty field name",
+			"=value1\r\n",
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range formContentTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			assert.NoError(t, err)
+			r.Header.Set("Content-Type", "text/plain")
+
+			results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+			assert.Equal(t, tt.expectedError, err != nil)
+		})
+	}
+}
+
+func TestGetFormContent_TextPlainDisabled(t *testing.T) {
+	r, err := http.NewRequest(http.MethodPost, "/", strings.NewReader("field1=value1\r\n"))
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "text/plain")
+
+	_, _, err = GetFormContent(httptest.NewRecorder(), r)
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusUnsupportedMediaType, pe.Status)
+}
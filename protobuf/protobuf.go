@@ -0,0 +1,129 @@
+// Package protobuf adds application/protobuf request bodies to formhandler, for message types
+// registered with RegisterProtoType. The proto parameter of the Content-Type header names the type:
+//
+//	protobuf.RegisterProtoType("signup.Signup", func() proto.Message { return &signuppb.Signup{} })
+//
+//	// Content-Type: application/protobuf; proto=signup.Signup
+//
+// Fields are keyed by their name in the .proto file, string and repeated string fields are kept
+// as they are and other scalars are coerced like CoerceScalars, while message, map and bytes
+// fields are rejected with a 400. Bodies of unregistered types are rejected with a 415.
+package protobuf
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"sync"
+
+	"github.com/charlesworth/formhandler"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// ContentType is the Content-Type of the request bodies parsed by this package
+	ContentType = "application/protobuf"
+
+	// typeParam is the Content-Type parameter naming the registered message type of a
+	// protobuf body, e.g. "application/protobuf; proto=signup.Signup"
+	typeParam = "proto"
+)
+
+// types holds the message factories registered with RegisterProtoType
+var types = struct {
+	sync.RWMutex
+	factories map[string]func() proto.Message
+}{factories: make(map[string]func() proto.Message)}
+
+func init() {
+	formhandler.RegisterBodyParser(ContentType, parse)
+}
+
+// RegisterProtoType registers the message type of application/protobuf bodies whose Content-Type
+// proto parameter is name, e.g. "application/protobuf; proto=signup.Signup". factory returns a
+// new message for every request. Registering a name again replaces its factory.
+func RegisterProtoType(name string, factory func() proto.Message) {
+	types.Lock()
+	defer types.Unlock()
+	types.factories[name] = factory
+}
+
+func lookupType(name string) (func() proto.Message, bool) {
+	types.RLock()
+	defer types.RUnlock()
+	factory, ok := types.factories[name]
+	return factory, ok
+}
+
+// parse unmarshals a protobuf body into the message type registered for the proto parameter of
+// the Content-Type header
+func parse(cfg formhandler.Config, r *http.Request, body io.Reader) (map[string][]string, *formhandler.ParseError) {
+	_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	factory, ok := lookupType(params[typeParam])
+	if !ok {
+		return nil, &formhandler.ParseError{Status: http.StatusUnsupportedMediaType, Msg: fmt.Sprintf(`Content-Type header %s has no registered protobuf message type "%s"`, ContentType, params[typeParam])}
+	}
+
+	content, readErr := ioutil.ReadAll(body)
+	if readErr != nil {
+		return nil, &formhandler.ParseError{Status: http.StatusBadRequest, Msg: "Request body could not be read", Err: readErr}
+	}
+
+	message := factory()
+	if unmarshalErr := proto.Unmarshal(content, message); unmarshalErr != nil {
+		return nil, &formhandler.ParseError{Status: http.StatusBadRequest, Msg: "Invalid protobuf body", Err: unmarshalErr}
+	}
+
+	fields := make(map[string]interface{})
+	message.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		fields[string(fd.Name())] = fieldValue(fd, value)
+		return true
+	})
+
+	cfg.CoerceScalars = true
+	return cfg.FlattenMap(fields, "Protobuf message")
+}
+
+// fieldValue converts a populated field to the types FlattenMap accepts, message, map and bytes
+// fields are returned as they are for FlattenMap to reject
+func fieldValue(fd protoreflect.FieldDescriptor, value protoreflect.Value) interface{} {
+	if fd.IsMap() {
+		return value.Map()
+	}
+	if fd.IsList() {
+		list := value.List()
+		values := make([]interface{}, 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			values = append(values, scalarValue(fd, list.Get(i)))
+		}
+		return values
+	}
+	return scalarValue(fd, value)
+}
+
+func scalarValue(fd protoreflect.FieldDescriptor, value protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return value.String()
+	case protoreflect.BoolKind:
+		return value.Bool()
+	case protoreflect.EnumKind:
+		if enumValue := fd.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+		return int64(value.Enum())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return value.Int()
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return value.Uint()
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return value.Float()
+	default:
+		return value.Interface()
+	}
+}
@@ -0,0 +1,101 @@
+package protobuf
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/charlesworth/formhandler"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/typepb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestParse(t *testing.T) {
+	RegisterProtoType("test.StringValue", func() proto.Message { return &wrapperspb.StringValue{} })
+	RegisterProtoType("test.FieldMask", func() proto.Message { return &fieldmaskpb.FieldMask{} })
+	RegisterProtoType("test.Duration", func() proto.Message { return &durationpb.Duration{} })
+	RegisterProtoType("test.Field", func() proto.Message { return &typepb.Field{} })
+	RegisterProtoType("test.Struct", func() proto.Message { return &structpb.Struct{} })
+
+	mustMarshal := func(m proto.Message) []byte {
+		b, err := proto.Marshal(m)
+		assert.NoError(t, err)
+		return b
+	}
+
+	var formContentTests = []struct {
+		testName             string
+		contentType          string
+		body                 []byte
+		expectedValuesOutput map[string][]string
+		expectedStatus       int
+	}{
+		{
+			"string field",
+			"application/protobuf; proto=test.StringValue",
+			mustMarshal(wrapperspb.String("value1")),
+			map[string][]string{"value": {"value1"}},
+			0,
+		},
+		{
+			"repeated string field",
+			"application/protobuf; proto=test.FieldMask",
+			mustMarshal(&fieldmaskpb.FieldMask{Paths: []string{"a", "b"}}),
+			map[string][]string{"paths": {"a", "b"}},
+			0,
+		},
+		{
+			"coerced integers",
+			"application/protobuf; proto=test.Duration",
+			mustMarshal(&durationpb.Duration{Seconds: 30, Nanos: -2}),
+			map[string][]string{"seconds": {"30"}, "nanos": {"-2"}},
+			0,
+		},
+		{
+			"coerced enums and booleans",
+			"application/protobuf; proto=test.Field",
+			mustMarshal(&typepb.Field{Kind: typepb.Field_TYPE_STRING, Name: "name", Packed: true}),
+			map[string][]string{"kind": {"TYPE_STRING"}, "name": {"name"}, "packed": {"true"}},
+			0,
+		},
+		{
+			"map field",
+			"application/protobuf; proto=test.Struct",
+			mustMarshal(&structpb.Struct{Fields: map[string]*structpb.Value{"a": structpb.NewStringValue("b")}}),
+			nil,
+			http.StatusBadRequest,
+		},
+		{"no fields set", "application/protobuf; proto=test.StringValue", []byte{}, nil, http.StatusBadRequest},
+		{"malformed", "application/protobuf; proto=test.StringValue", []byte{0x0a, 0x05, 'a'}, nil, http.StatusBadRequest},
+		{"unregistered type", "application/protobuf; proto=test.Missing", mustMarshal(wrapperspb.String("value1")), nil, http.StatusUnsupportedMediaType},
+		{"no type", "application/protobuf", mustMarshal(wrapperspb.String("value1")), nil, http.StatusUnsupportedMediaType},
+		{"too large", "application/protobuf; proto=test.StringValue", mustMarshal(wrapperspb.String(strings.Repeat("a", 2_000_000))), nil, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range formContentTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			assert.NoError(t, err)
+			r.Header.Set("Content-Type", tt.contentType)
+
+			results, _, err := formhandler.GetFormContent(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+			if tt.expectedStatus != 0 {
+				var pe *formhandler.ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, tt.expectedStatus, pe.Status)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package formhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// WriteError writes err as a plain text response. A *ParseError is written with its status
+// and message, any other error is written as a generic 500 so internal error details are
+// never sent to the client.
+func WriteError(w http.ResponseWriter, err error) {
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		http.Error(w, pe.Msg, pe.Status)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// MarshalJSON encodes the error as {"error": message, "status": status}
+func (pe *ParseError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Error  string `json:"error"`
+		Status int    `json:"status"`
+	}{pe.Msg, pe.Status})
+}
+
+// WriteJSONError writes err as a JSON response in the form {"error": message, "status": status}.
+// A *ParseError is written with its status and message, any other error is written as a generic
+// 500 so internal error details are never sent to the client.
+func WriteJSONError(w http.ResponseWriter, err error) {
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		pe = &ParseError{Status: http.StatusInternalServerError, Msg: http.StatusText(http.StatusInternalServerError)}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(pe.Status)
+	json.NewEncoder(w).Encode(pe)
+}
@@ -0,0 +1,89 @@
+package formhandler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteError(t *testing.T) {
+	var writeErrorTests = []struct {
+		testName       string
+		err            error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"parse error",
+			&ParseError{Status: http.StatusBadRequest, Msg: "Request body must not be empty"},
+			http.StatusBadRequest,
+			"Request body must not be empty\n",
+		},
+		{
+			"wrapped parse error",
+			fmt.Errorf("handling form: %w", &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}),
+			http.StatusRequestEntityTooLarge,
+			"Request body too large\n",
+		},
+		{
+			"other error",
+			errors.New("database password is hunter2"),
+			http.StatusInternalServerError,
+			"Internal Server Error\n",
+		},
+	}
+
+	for _, tt := range writeErrorTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			WriteError(w, tt.err)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestWriteJSONError(t *testing.T) {
+	var writeErrorTests = []struct {
+		testName       string
+		err            error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"parse error",
+			&ParseError{Status: http.StatusBadRequest, Msg: "Request body must not be empty"},
+			http.StatusBadRequest,
+			`{"error":"Request body must not be empty","status":400}`,
+		},
+		{
+			"message needing escaping",
+			&ParseError{Status: http.StatusBadRequest, Msg: `JSON object contains invalid value for field "field1", cannot use an empty string`},
+			http.StatusBadRequest,
+			`{"error":"JSON object contains invalid value for field \"field1\", cannot use an empty string","status":400}`,
+		},
+		{
+			"other error",
+			errors.New("database password is hunter2"),
+			http.StatusInternalServerError,
+			`{"error":"Internal Server Error","status":500}`,
+		},
+	}
+
+	for _, tt := range writeErrorTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			WriteJSONError(w, tt.err)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		})
+	}
+}
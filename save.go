@@ -0,0 +1,138 @@
+package formhandler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CreateFunc creates a named file for writing, it abstracts the filesystem the uploaded files
+// are persisted to, for example a wrapper around os.Create, an in-memory filesystem in tests
+// or an object storage upload writer
+type CreateFunc func(name string) (io.WriteCloser, error)
+
+// SaveFiles writes every uploaded file into dir, returning the paths the files were saved to
+// keyed by their form field. File names are the client filenames reduced to their final path
+// element, so they cannot escape dir, with a numeric suffix added when a file of the same name
+// already exists. If any file fails to save, the files already written are removed.
+func SaveFiles(files map[string][]*multipart.FileHeader, dir string) (saved map[string][]string, err error) {
+	var written []string
+	defer func() {
+		if err != nil {
+			for _, path := range written {
+				os.Remove(path)
+			}
+		}
+	}()
+
+	names, err := SaveFilesWith(files, func(name string) (io.WriteCloser, error) {
+		path := filepath.Join(dir, name)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			written = append(written, path)
+		}
+		return f, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fieldNames := range names {
+		for i, name := range fieldNames {
+			fieldNames[i] = filepath.Join(dir, name)
+		}
+	}
+	return names, nil
+}
+
+// SaveFilesWith writes every uploaded file using create, returning the names the files were
+// saved under keyed by their form field. Names are the sanitized client filenames, made unique
+// within the call by adding a numeric suffix. When create returns an error matching
+// fs.ErrExist the next suffix is tried, so create can refuse to overwrite existing files.
+func SaveFilesWith(files map[string][]*multipart.FileHeader, create CreateFunc) (saved map[string][]string, err error) {
+	saved = make(map[string][]string, len(files))
+	used := make(map[string]bool)
+
+	// iterate in field order so the names given to duplicate filenames are deterministic
+	fields := make([]string, 0, len(files))
+	for field := range files {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		for _, header := range files[field] {
+			filename := sanitizeFilename(header.Filename)
+			name := uniqueFilename(filename, used)
+			err := saveFile(header, name, create)
+			for errors.Is(err, fs.ErrExist) {
+				name = uniqueFilename(filename, used)
+				err = saveFile(header, name, create)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("saving file %q of field %q: %w", header.Filename, field, err)
+			}
+			saved[field] = append(saved[field], name)
+		}
+	}
+
+	return saved, nil
+}
+
+func saveFile(header *multipart.FileHeader, name string, create CreateFunc) error {
+	src, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := create(name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// sanitizeFilename reduces a client provided filename to its final path element, so it
+// cannot be used to traverse directories, returning "file" for names without a usable one
+func sanitizeFilename(filename string) string {
+	name, ok := baseFilename(filename)
+	if !ok {
+		return "file"
+	}
+	return name
+}
+
+// baseFilename returns the final path element of a client provided filename, and false if it
+// has none that can be used as a filename. Windows style separators are also stripped as some
+// browsers send the full client path. path.Base is used rather than filepath.Base so names are
+// reduced the same way on every platform.
+func baseFilename(filename string) (string, bool) {
+	name := path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	return name, name != "." && name != ".." && name != "/"
+}
+
+// uniqueFilename returns name, or name with a numeric suffix before its extension
+// if it has already been used, and marks the returned name as used
+func uniqueFilename(name string, used map[string]bool) string {
+	unique := name
+	ext := filepath.Ext(name)
+	for i := 1; used[unique]; i++ {
+		unique = strings.TrimSuffix(name, ext) + "-" + strconv.Itoa(i) + ext
+	}
+	used[unique] = true
+	return unique
+}
@@ -0,0 +1,106 @@
+package formhandler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryFile struct {
+	bytes.Buffer
+}
+
+func (mf *memoryFile) Close() error { return nil }
+
+func TestSaveFilesWith(t *testing.T) {
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "file1", fileName: "../../etc/passwd", value: "traversal"},
+		{field: "file1", fileName: "photo.png", value: "first"},
+		{field: "file2", fileName: `C:\Users\charlie\photo.png`, value: "second"},
+		{field: "file2", fileName: "..", value: "dots"},
+	})
+	assert.NoError(t, err)
+	// keep the raw filenames to check SaveFilesWith sanitizes them itself
+	cfg := defaultConfig()
+	cfg.KeepRawFilenames = true
+	_, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+
+	fs := map[string]*memoryFile{}
+	saved, err := SaveFilesWith(files, func(name string) (io.WriteCloser, error) {
+		fs[name] = &memoryFile{}
+		return fs[name], nil
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string][]string{
+		"file1": {"passwd", "photo.png"},
+		"file2": {"photo-1.png", "file"},
+	}, saved)
+	assert.Equal(t, "traversal", fs["passwd"].String())
+	assert.Equal(t, "first", fs["photo.png"].String())
+	assert.Equal(t, "second", fs["photo-1.png"].String())
+	assert.Equal(t, "dots", fs["file"].String())
+
+	createErr := errors.New("read-only filesystem")
+	saved, err = SaveFilesWith(files, func(name string) (io.WriteCloser, error) {
+		return nil, createErr
+	})
+	assert.Nil(t, saved)
+	assert.True(t, errors.Is(err, createErr))
+}
+
+func TestSaveFiles(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "photo.png"), []byte("existing"), 0o644))
+
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "file1", fileName: "../../photo.png", value: "first"},
+		{field: "file1", fileName: "notes.txt", value: "notes"},
+	})
+	assert.NoError(t, err)
+	_, files, err := GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+
+	saved, err := SaveFiles(files, dir)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"file1": {filepath.Join(dir, "photo-1.png"), filepath.Join(dir, "notes.txt")},
+	}, saved)
+
+	for path, expected := range map[string]string{"photo.png": "existing", "photo-1.png": "first", "notes.txt": "notes"} {
+		content, err := ioutil.ReadFile(filepath.Join(dir, path))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+	}
+}
+
+func TestSaveFiles_CleanupOnFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "a", fileName: "a.txt", value: "a"},
+		{field: "b", fileName: "b.txt", value: "b"},
+	})
+	assert.NoError(t, err)
+	_, files, err := GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+
+	// a name longer than the filesystem allows makes the second file fail to save
+	files["b"][0].Filename = strings.Repeat("b", 300) + ".txt"
+
+	saved, err := SaveFiles(files, dir)
+	assert.Error(t, err)
+	assert.Nil(t, saved)
+
+	_, statErr := os.Stat(filepath.Join(dir, "a.txt"))
+	assert.True(t, os.IsNotExist(statErr), "files saved before the failure should be removed")
+}
@@ -0,0 +1,61 @@
+package formhandler
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// Schema maps form field names to the rules their values must follow
+type Schema map[string]FieldSpec
+
+// FieldSpec is the rules for the values of a single form field
+type FieldSpec struct {
+	// Required rejects forms without a value for the field
+	Required bool
+	// MaxLen is the maximum number of characters each value may have, zero is unlimited
+	MaxLen int
+	// Pattern, when set, must match every value
+	Pattern *regexp.Regexp
+	// AllowMultiple allows the field to have more than one value
+	AllowMultiple bool
+}
+
+// Validate checks the results against the schema, returning the first violation as a 400
+// naming the field and the rule it broke. Fields are checked in alphabetical order so the
+// violation returned is stable, and fields missing from the schema are not checked.
+func Validate(results map[string][]string, schema Schema) *ParseError {
+	fields := make([]string, 0, len(schema))
+	for field := range schema {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		spec := schema[field]
+		values := results[field]
+
+		if len(values) == 0 {
+			if spec.Required {
+				return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" is required`, field)}
+			}
+			continue
+		}
+
+		if len(values) > 1 && !spec.AllowMultiple {
+			return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" must have a single value`, field)}
+		}
+
+		for _, value := range values {
+			if spec.MaxLen > 0 && utf8.RuneCountInString(value) > spec.MaxLen {
+				return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" must be at most %d characters`, field, spec.MaxLen)}
+			}
+			if spec.Pattern != nil && !spec.Pattern.MatchString(value) {
+				return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" does not match required pattern`, field)}
+			}
+		}
+	}
+	return nil
+}
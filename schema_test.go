@@ -0,0 +1,45 @@
+package formhandler
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	schema := Schema{
+		"email": {Required: true, Pattern: regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)},
+		"name":  {Required: true, MaxLen: 5},
+		"tags":  {AllowMultiple: true, MaxLen: 3},
+		"notes": {},
+	}
+
+	var validateTests = []struct {
+		testName    string
+		results     map[string][]string
+		expectedMsg string
+	}{
+		{"valid", map[string][]string{"email": {"a@b.c"}, "name": {"émile"}, "tags": {"a", "bcd"}}, ""},
+		{"unknown fields not checked", map[string][]string{"email": {"a@b.c"}, "name": {"bob"}, "other": {"x", "y"}}, ""},
+		{"missing required", map[string][]string{"name": {"bob"}}, `Form field "email" is required`},
+		{"pattern mismatch", map[string][]string{"email": {"not an email"}, "name": {"bob"}}, `Form field "email" does not match required pattern`},
+		{"too long", map[string][]string{"email": {"a@b.c"}, "name": {"robert"}}, `Form field "name" must be at most 5 characters`},
+		{"multiple values", map[string][]string{"email": {"a@b.c"}, "name": {"bob"}, "notes": {"a", "b"}}, `Form field "notes" must have a single value`},
+		{"every value checked", map[string][]string{"email": {"a@b.c"}, "name": {"bob"}, "tags": {"a", "long"}}, `Form field "tags" must be at most 3 characters`},
+		{"first field alphabetically", map[string][]string{"email": {"bad"}}, `Form field "email" does not match required pattern`},
+	}
+
+	for _, tt := range validateTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			pe := Validate(tt.results, schema)
+			if tt.expectedMsg == "" {
+				assert.Nil(t, pe)
+			} else if assert.NotNil(t, pe) {
+				assert.Equal(t, http.StatusBadRequest, pe.Status)
+				assert.Equal(t, tt.expectedMsg, pe.Msg)
+			}
+		})
+	}
+}
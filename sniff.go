@@ -0,0 +1,46 @@
+package formhandler
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+)
+
+// sniffPeekLen is the number of body bytes SniffContentType looks through for the first byte
+// that isn't whitespace
+const sniffPeekLen = 512
+
+// bufferedBody is a request body read through a bufio.Reader, so bytes peeked at are read again
+type bufferedBody struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// sniffBodyContentType guesses the content type of a request without a Content-Type header from
+// the first byte of its body that isn't whitespace. "{" or "[" is JSON, another printable ASCII
+// character is a URL encoded form, and an empty body or any other byte returns "". The body is
+// replaced so the peeked bytes are still read by the parser.
+func sniffBodyContentType(r *http.Request) string {
+	if r.Body == nil || r.Body == http.NoBody {
+		return ""
+	}
+
+	buffered := bufio.NewReaderSize(r.Body, sniffPeekLen)
+	r.Body = bufferedBody{Reader: buffered, Closer: r.Body}
+
+	// a short body returns fewer bytes with an error, which the parser will see again
+	peeked, _ := buffered.Peek(sniffPeekLen)
+	for _, b := range peeked {
+		switch {
+		case b == ' ' || b == '\t' || b == '\r' || b == '\n':
+			continue
+		case b == '{' || b == '[':
+			return headerValApplicationJSON
+		case b > ' ' && b < 0x7f:
+			return headerValFormURLEncoded
+		default:
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package formhandler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+// StreamMultipart parses a multipart/form-data request part by part without buffering files in
+// memory or on disk. Each file part is passed to onFile as it is read, which must consume the
+// part before returning if it needs its content, while value parts are collected into the
+// results. If onFile returns an error streaming stops, a *ParseError is returned as is and
+// any other error is returned wrapped in a *ParseError with a 500. Streaming also stops with
+// a 408 if the request context is cancelled between parts, or if a part takes longer than
+// PerPartTimeout to read.
+func StreamMultipart(r *http.Request, onFile func(field string, part *multipart.Part) error) (results map[string][]string, err error) {
+	return defaultConfig().StreamMultipart(r, onFile)
+}
+
+// StreamMultipart operates the same as the package level StreamMultipart, using the options set
+// on the Config. The values of the form, but not its files, are limited to MaxFormSize bytes,
+// and with MaxValueLength a value is only read up to the limit, a longer one stopping streaming
+// with a 413 naming the field.
+//
+// Once the body is consumed the values are checked and transformed by RejectNullBytes,
+// RequireValidUTF8, TrimSpace, ValueTransform, KeepEmptyFields, MaxValueLength and DateFields.
+// Files are passed to onFile before every field is known, so no other option applies: fields
+// aren't renamed or checked against AllowedFields, MaxFields or RejectUnsafeKeyRunes, and the
+// file checks, such as AllowedFileExtensions, are left to onFile.
+func (cfg Config) StreamMultipart(r *http.Request, onFile func(field string, part *multipart.Part) error) (results map[string][]string, err error) {
+	cfg = cfg.withDefaults()
+	contentType := cfg.getContentType(r)
+	if contentType != headerValFormMultipart {
+		if contentType == "" {
+			return nil, missingContentTypeError()
+		}
+		return nil, unsupportedContentTypeError(contentType)
+	}
+
+	var timed *deadlineReader
+	if cfg.PerPartTimeout > 0 {
+		timed = &deadlineReader{ReadCloser: r.Body}
+		r.Body = timed
+	}
+
+	reader, readerErr := r.MultipartReader()
+	if readerErr != nil {
+		return nil, &ParseError{Status: http.StatusBadRequest, Msg: `Invalid multipart form`}
+	}
+
+	results = make(map[string][]string)
+	remaining := cfg.MaxFormSize
+	for {
+		if ctxErr := r.Context().Err(); ctxErr != nil {
+			return nil, &ParseError{Status: http.StatusRequestTimeout, Msg: "Request cancelled before the form was parsed", Err: ctxErr}
+		}
+
+		if timed != nil {
+			timed.deadline = time.Now().Add(cfg.PerPartTimeout)
+		}
+
+		part, partErr := reader.NextPart()
+		if partErr == io.EOF {
+			break
+		}
+		if timed != nil && timed.expired {
+			return nil, partTimeoutError()
+		}
+		if partErr != nil {
+			return nil, &ParseError{Status: http.StatusBadRequest, Msg: `Invalid multipart form`}
+		}
+
+		field := part.FormName()
+		if field == "" {
+			continue
+		}
+
+		if part.FileName() != "" {
+			callbackErr := onFile(field, part)
+			if timed != nil && timed.expired {
+				return nil, partTimeoutError()
+			}
+			if callbackErr != nil {
+				var pe *ParseError
+				if errors.As(callbackErr, &pe) {
+					return nil, pe
+				}
+				return nil, &ParseError{Status: http.StatusInternalServerError, Msg: fmt.Sprintf(`File "%s" of field "%s" could not be processed`, part.FileName(), field), Err: callbackErr}
+			}
+			continue
+		}
+
+		// a value is read no further than MaxValueLength, so an oversized one isn't buffered whole
+		limit := remaining + 1
+		if cfg.MaxValueLength > 0 && int64(cfg.MaxValueLength)+1 < limit {
+			limit = int64(cfg.MaxValueLength) + 1
+		}
+		value, readErr := ioutil.ReadAll(io.LimitReader(part, limit))
+		if timed != nil && timed.expired {
+			return nil, partTimeoutError()
+		}
+		if readErr != nil {
+			return nil, &ParseError{Status: http.StatusBadRequest, Msg: `Invalid multipart form`}
+		}
+		if cfg.MaxValueLength > 0 && len(value) > cfg.MaxValueLength {
+			return nil, &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: fmt.Sprintf(`Form field "%s" has a value longer than %d bytes`, field, cfg.MaxValueLength)}
+		}
+		remaining -= int64(len(value))
+		if remaining < 0 {
+			return nil, &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}
+		}
+		results[field] = append(results[field], string(value))
+	}
+
+	if _, parseErr := cfg.processValues(results, contentType); parseErr != nil {
+		return nil, parseErr
+	}
+	if cfg.MaxValueLength > 0 {
+		if parseErr := validateValueLengths(results, cfg.MaxValueLength); parseErr != nil {
+			return nil, parseErr
+		}
+	}
+	if parseErr := validateDateFields(results, cfg.DateFields); parseErr != nil {
+		return nil, parseErr
+	}
+	return results, nil
+}
+
+// partTimeoutError is returned when a part of a streamed multipart body isn't read before its
+// PerPartTimeout
+func partTimeoutError() *ParseError {
+	return &ParseError{Status: http.StatusRequestTimeout, Msg: "Multipart part was not read before the timeout"}
+}
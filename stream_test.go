@@ -0,0 +1,221 @@
+package formhandler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamMultipart(t *testing.T) {
+	large := strings.Repeat("x", 2*megabyte)
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "field1", value: "value1"},
+		{field: "file1", fileName: "small.txt", value: "small"},
+		{field: "field2", value: ""},
+		{field: "file1", fileName: "large.txt", value: large},
+		{field: "field1", value: "value2"},
+	})
+	assert.NoError(t, err)
+
+	type streamedFile struct {
+		field, fileName, content string
+	}
+	var streamed []streamedFile
+	results, err := StreamMultipart(r, func(field string, part *multipart.Part) error {
+		content, err := ioutil.ReadAll(part)
+		streamed = append(streamed, streamedFile{field, part.FileName(), string(content)})
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1", "value2"}}, results)
+	assert.Equal(t, []streamedFile{
+		{"file1", "small.txt", "small"},
+		{"file1", "large.txt", large},
+	}, streamed)
+}
+
+func TestStreamMultipart_ValueOptions(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.TrimSpace = true
+	cfg.ValueTransform = func(field, value string) (string, error) {
+		return strings.ToUpper(value), nil
+	}
+
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "field1", value: "  value1 "},
+		{field: "field2", value: "   "},
+		{field: "file1", fileName: "one.txt", value: "one"},
+	})
+	assert.NoError(t, err)
+	results, err := cfg.StreamMultipart(r, func(field string, part *multipart.Part) error { return nil })
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"VALUE1"}}, results)
+
+	// value checks reject the form
+	cfg = defaultConfig()
+	cfg.RejectNullBytes = true
+	r, err = constructMultipartFormParts([]multipartPart{{field: "field1", value: "value\x001"}})
+	assert.NoError(t, err)
+	_, err = cfg.StreamMultipart(r, func(field string, part *multipart.Part) error { return nil })
+	var pe *ParseError
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusBadRequest, pe.Status)
+}
+
+func TestStreamMultipart_MaxValueLength(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.MaxValueLength = 10
+
+	// streaming stops at the oversized value, before the parts after it are read
+	var streamed []string
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "field1", value: "value1"},
+		{field: "field2", value: strings.Repeat("x", megabyte)},
+		{field: "file1", fileName: "one.txt", value: "one"},
+	})
+	assert.NoError(t, err)
+	results, err := cfg.StreamMultipart(r, func(field string, part *multipart.Part) error {
+		streamed = append(streamed, field)
+		return nil
+	})
+	assert.Nil(t, results)
+	assert.Empty(t, streamed)
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, pe.Status)
+		assert.Equal(t, `Form field "field2" has a value longer than 10 bytes`, pe.Msg)
+	}
+
+	// a value of exactly MaxValueLength bytes is accepted
+	r, err = constructMultipartFormParts([]multipartPart{{field: "field1", value: "0123456789"}})
+	assert.NoError(t, err)
+	results, err = cfg.StreamMultipart(r, func(field string, part *multipart.Part) error { return nil })
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"0123456789"}}, results)
+}
+
+// partReader returns the parts of a multipart body a read at a time, waiting delay before each
+type partReader struct {
+	parts []string
+	delay time.Duration
+}
+
+func (pr *partReader) Read(p []byte) (int, error) {
+	if len(pr.parts) == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(pr.delay)
+	n := copy(p, pr.parts[0])
+	if pr.parts[0] = pr.parts[0][n:]; pr.parts[0] == "" {
+		pr.parts = pr.parts[1:]
+	}
+	return n, nil
+}
+
+func TestStreamMultipart_PerPartTimeout(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.PerPartTimeout = 100 * time.Millisecond
+	onFile := func(field string, part *multipart.Part) error {
+		_, err := ioutil.ReadAll(part)
+		return err
+	}
+	const contentType = "multipart/form-data; boundary=b"
+
+	// the timeout applies to each part, not the whole body
+	r := httptest.NewRequest(http.MethodPost, "/", &partReader{delay: 30 * time.Millisecond, parts: []string{
+		"--b\r\nContent-Disposition: form-data; name=\"field1\"\r\n\r\nvalue1\r\n",
+		"--b\r\nContent-Disposition: form-data; name=\"file1\"; filename=\"a.txt\"\r\n\r\nsome\r\n",
+		"--b\r\nContent-Disposition: form-data; name=\"field1\"\r\n\r\nvalue2\r\n",
+		"--b\r\nContent-Disposition: form-data; name=\"field2\"\r\n\r\nvalue3\r\n",
+		"--b\r\nContent-Disposition: form-data; name=\"field3\"\r\n\r\nvalue4\r\n",
+		"--b--\r\n",
+	}})
+	r.Header.Set("Content-Type", contentType)
+	start := time.Now()
+	results, err := cfg.StreamMultipart(r, onFile)
+	assert.NoError(t, err)
+	assert.Greater(t, int64(time.Since(start)), int64(cfg.PerPartTimeout))
+	assert.Equal(t, map[string][]string{"field1": {"value1", "value2"}, "field2": {"value3"}, "field3": {"value4"}}, results)
+
+	// a value or file part trickled in is rejected once the timeout passes
+	for _, part := range []string{
+		"--b\r\nContent-Disposition: form-data; name=\"field1\"\r\n\r\n" + strings.Repeat("x", 100),
+		"--b\r\nContent-Disposition: form-data; name=\"file1\"; filename=\"a.txt\"\r\n\r\n" + strings.Repeat("x", 100),
+	} {
+		r = httptest.NewRequest(http.MethodPost, "/", &tricklingReader{content: part, delay: 5 * time.Millisecond})
+		r.Header.Set("Content-Type", contentType)
+		results, err = cfg.StreamMultipart(r, onFile)
+		assert.Nil(t, results)
+		var pe *ParseError
+		if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+			assert.Equal(t, http.StatusRequestTimeout, pe.Status)
+		}
+	}
+}
+
+func TestStreamMultipart_Errors(t *testing.T) {
+	var pe *ParseError
+
+	// a callback error stops streaming and is wrapped
+	r, err := constructMultipartFormParts([]multipartPart{
+		{field: "file1", fileName: "one.txt", value: "one"},
+		{field: "file2", fileName: "two.txt", value: "two"},
+	})
+	assert.NoError(t, err)
+	uploadErr := errors.New("upload failed")
+	calls := 0
+	_, err = StreamMultipart(r, func(field string, part *multipart.Part) error {
+		calls++
+		return uploadErr
+	})
+	assert.Equal(t, 1, calls)
+	assert.True(t, errors.Is(err, uploadErr))
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusInternalServerError, pe.Status)
+	assert.NotContains(t, pe.Msg, uploadErr.Error())
+
+	// a *ParseError from the callback is returned as is
+	r, err = constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "one.txt", value: "one"}})
+	assert.NoError(t, err)
+	rejected := &ParseError{Status: http.StatusUnsupportedMediaType, Msg: "Unsupported file"}
+	_, err = StreamMultipart(r, func(field string, part *multipart.Part) error {
+		return rejected
+	})
+	assert.Equal(t, rejected, err)
+
+	// streaming stops once the request is cancelled
+	r, err = constructMultipartFormParts([]multipartPart{{field: "file1", fileName: "one.txt", value: "one"}})
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(r.Context())
+	cancel()
+	_, err = StreamMultipart(r.WithContext(ctx), func(field string, part *multipart.Part) error { return nil })
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusRequestTimeout, pe.Status)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	// values are limited to MaxFormSize
+	cfg := defaultConfig()
+	cfg.MaxFormSize = 8
+	r, err = constructMultipartFormParts([]multipartPart{{field: "field1", value: "value1"}, {field: "field2", value: "value2"}})
+	assert.NoError(t, err)
+	_, err = cfg.StreamMultipart(r, func(field string, part *multipart.Part) error { return nil })
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, pe.Status)
+
+	// other content types aren't supported
+	r, err = constructURLEncodedForm(url.Values{"field1": {"value1"}})
+	assert.NoError(t, err)
+	_, err = StreamMultipart(r, func(field string, part *multipart.Part) error { return nil })
+	assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+	assert.Equal(t, http.StatusUnsupportedMediaType, pe.Status)
+}
@@ -0,0 +1,76 @@
+package formhandler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// errReadDeadline is returned by reads of a body once ParseTimeout or PerPartTimeout has passed
+var errReadDeadline = errors.New("formhandler: read deadline exceeded")
+
+// parseWithTimeout parses the request, rejecting it with a 408 once ParseTimeout passes. The
+// deadline is set on the connection when the ResponseWriter supports read deadlines, as those of
+// net/http servers since Go 1.20 do, so a read blocked on a slow client fails once it passes.
+// Otherwise it's only checked before each read of the body.
+func (cfg Config) parseWithTimeout(w http.ResponseWriter, r *http.Request, body *countingReader, contentType string) (*Form, *ParseError) {
+	deadline := time.Now().Add(cfg.ParseTimeout)
+	deadliner := readDeadliner(w)
+	if deadliner != nil && deadliner.SetReadDeadline(deadline) != nil {
+		deadliner = nil
+	}
+
+	timed := &deadlineReader{ReadCloser: r.Body, deadline: deadline}
+	r.Body = timed
+	form, parseErr := cfg.parseOrRemoveFiles(w, r, body, contentType)
+
+	if timed.expired {
+		if form != nil {
+			form.Cleanup()
+		}
+		// the connection can't be read from again, so it isn't kept alive for another request
+		w.Header().Set("Connection", "close")
+		return nil, &ParseError{Status: http.StatusRequestTimeout, Msg: "Request body was not parsed before the timeout"}
+	}
+	if deadliner != nil {
+		deadliner.SetReadDeadline(time.Time{})
+	}
+	return form, parseErr
+}
+
+// readDeadliner returns the ResponseWriter, or one it wraps, able to set the read deadline of
+// the connection, the same as http.ResponseController finds it, or nil if there's none
+func readDeadliner(w http.ResponseWriter) interface{ SetReadDeadline(time.Time) error } {
+	for {
+		if deadliner, ok := w.(interface{ SetReadDeadline(time.Time) error }); ok {
+			return deadliner
+		}
+		wrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = wrapper.Unwrap()
+	}
+}
+
+// deadlineReader fails reads of a request body once its deadline has passed, recording whether
+// the deadline stopped the body being read, including by a read deadline on the connection
+type deadlineReader struct {
+	io.ReadCloser
+	deadline time.Time
+	expired  bool
+}
+
+func (dr *deadlineReader) Read(p []byte) (int, error) {
+	if !time.Now().Before(dr.deadline) {
+		dr.expired = true
+		return 0, errReadDeadline
+	}
+	n, err := dr.ReadCloser.Read(p)
+	if err != nil && errors.Is(err, os.ErrDeadlineExceeded) {
+		dr.expired = true
+	}
+	return n, err
+}
@@ -0,0 +1,113 @@
+package formhandler
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tricklingReader returns its content a byte at a time, waiting delay before each byte
+type tricklingReader struct {
+	content string
+	delay   time.Duration
+}
+
+func (tr *tricklingReader) Read(p []byte) (int, error) {
+	time.Sleep(tr.delay)
+	if tr.content == "" {
+		return 0, errors.New("trickling reader exhausted")
+	}
+	n := copy(p[:1], tr.content)
+	tr.content = tr.content[n:]
+	return n, nil
+}
+
+func TestParseTimeout(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.ParseTimeout = 50 * time.Millisecond
+	cfg.WarnDroppedFields = true
+
+	// a request parsed in time is unaffected, including the headers set while parsing
+	r, err := constructURLEncodedForm(url.Values{"field1": {"value1"}, "field2": {""}})
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	results, _, err := cfg.GetFormContent(w, r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}}, results)
+	assert.Contains(t, w.Header().Get("Warning"), "field2")
+
+	// a body trickling in is rejected once the timeout passes
+	r, err = http.NewRequest(http.MethodPost, "/", &tricklingReader{content: `{"field1": "value1"}`, delay: 10 * time.Millisecond})
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	w = httptest.NewRecorder()
+	results, _, err = cfg.GetFormContent(w, r)
+	assert.Nil(t, results)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusRequestTimeout, pe.Status)
+	}
+	assert.Equal(t, "close", w.Header().Get("Connection"))
+}
+
+func TestParseTimeout_Server(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.ParseTimeout = 50 * time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := cfg.GetFormContent(w, r); err != nil {
+			WriteError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var timeoutTests = []struct {
+		testName    string
+		contentType string
+		body        string
+	}{
+		{"url encoded", "application/x-www-form-urlencoded", "field1=val"},
+		{"multipart", "multipart/form-data; boundary=b", "--b\r\nContent-Disposition: form-data; name=\"file1\"; filename=\"a.txt\"\r\n\r\nsome"},
+	}
+
+	for _, tt := range timeoutTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			// the client sends part of the body and then stalls, without closing the connection
+			conn, err := net.Dial("tcp", server.Listener.Addr().String())
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer conn.Close()
+			fmt.Fprintf(conn, "POST / HTTP/1.1\r\nHost: example.com\r\nContent-Type: %s\r\nContent-Length: 1000\r\n\r\n%s", tt.contentType, tt.body)
+
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			if assert.NoError(t, err, "the 408 reaches the stalled client") {
+				resp.Body.Close()
+				assert.Equal(t, http.StatusRequestTimeout, resp.StatusCode)
+				assert.True(t, resp.Close)
+			}
+		})
+	}
+
+	// a request sent in full is parsed as usual
+	resp, err := http.Post(server.URL, "application/x-www-form-urlencoded", strings.NewReader("field1=value1"))
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	}
+}
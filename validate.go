@@ -0,0 +1,216 @@
+package formhandler
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// validateKeyRunes rejects field names containing control or format characters, such as
+// zero-width spaces or bidirectional overrides, which can be used to spoof field names
+func validateKeyRunes(results map[string][]string, files map[string][]*multipart.FileHeader) *ParseError {
+	for field := range results {
+		if parseErr := validateKey(field); parseErr != nil {
+			return parseErr
+		}
+	}
+	for field := range files {
+		if parseErr := validateKey(field); parseErr != nil {
+			return parseErr
+		}
+	}
+	return nil
+}
+
+func validateKey(field string) *ParseError {
+	for _, r := range field {
+		if unicode.In(r, unicode.Cc, unicode.Cf) {
+			return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Form field name %s contains the unsafe character %U", strconv.QuoteToASCII(field), r)}
+		}
+	}
+	return nil
+}
+
+// validateAllowedFields rejects value or file fields that are not in the allowed list,
+// naming every unknown field in the error
+func validateAllowedFields(results map[string][]string, files map[string][]*multipart.FileHeader, allowed []string) *ParseError {
+	var unknown []string
+	for field := range results {
+		if !containsString(allowed, field) {
+			unknown = append(unknown, strconv.Quote(field))
+		}
+	}
+	for field := range files {
+		if !containsString(allowed, field) {
+			unknown = append(unknown, strconv.Quote(field))
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Form contains unknown fields %s", strings.Join(unknown, ", "))}
+	}
+	return nil
+}
+
+// renameField gives the name of a field after FieldRename, and if it's kept
+func (cfg Config) renameField(field string) (string, bool) {
+	if name, ok := cfg.FieldRename[field]; ok {
+		return name, true
+	}
+	return field, !cfg.DropUnmapped
+}
+
+// resultKey gives the name a field sent by the client has in the results after
+// CaseInsensitiveKeys and FieldRename, and if it's kept
+func (cfg Config) resultKey(field string) (string, bool) {
+	if cfg.CaseInsensitiveKeys {
+		field, _ = lowercaseKey(field)
+	}
+	if cfg.FieldRename != nil {
+		return cfg.renameField(field)
+	}
+	return field, true
+}
+
+// validateFieldCount rejects forms with more than maxFields distinct value and file fields
+func validateFieldCount(results map[string][]string, files map[string][]*multipart.FileHeader, maxFields int) *ParseError {
+	count := len(results)
+	for field := range files {
+		if _, ok := results[field]; !ok {
+			count++
+		}
+	}
+
+	if count > maxFields {
+		return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Form contains %d fields, more than the limit of %d", count, maxFields)}
+	}
+	return nil
+}
+
+// validateFileCount rejects multipart forms with more than maxFiles files across all fields
+func validateFileCount(files map[string][]*multipart.FileHeader, maxFiles int) *ParseError {
+	count := 0
+	for _, headers := range files {
+		count += len(headers)
+	}
+
+	if count > maxFiles {
+		return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Form contains %d files, more than the limit of %d", count, maxFiles)}
+	}
+	return nil
+}
+
+// validateValueLengths rejects forms with a value longer than maxValueLength bytes, naming the
+// first offending field in alphabetical order so the error is stable
+func validateValueLengths(results map[string][]string, maxValueLength int) *ParseError {
+	var tooLong []string
+	for field, values := range results {
+		for _, value := range values {
+			if len(value) > maxValueLength {
+				tooLong = append(tooLong, field)
+				break
+			}
+		}
+	}
+
+	if len(tooLong) > 0 {
+		sort.Strings(tooLong)
+		return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" has a value longer than %d bytes`, tooLong[0], maxValueLength)}
+	}
+	return nil
+}
+
+// validateNoNullBytes rejects forms with a value containing a null byte, naming the first
+// offending field in alphabetical order so the error is stable
+func validateNoNullBytes(results map[string][]string) *ParseError {
+	for _, field := range sortedFields(results) {
+		for _, value := range results[field] {
+			if strings.IndexByte(value, 0) >= 0 {
+				return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" contains a null byte`, field)}
+			}
+		}
+	}
+	return nil
+}
+
+// validateUTF8 rejects forms with a value that isn't valid UTF-8, naming the first offending
+// field in alphabetical order so the error is stable
+func validateUTF8(results map[string][]string) *ParseError {
+	for _, field := range sortedFields(results) {
+		for _, value := range results[field] {
+			if !utf8.ValidString(value) {
+				return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" contains invalid UTF-8`, field)}
+			}
+		}
+	}
+	return nil
+}
+
+// trimValues removes leading and trailing whitespace from every value
+func trimValues(results map[string][]string) {
+	for _, values := range results {
+		for i, value := range values {
+			values[i] = strings.TrimSpace(value)
+		}
+	}
+}
+
+// validateNoEmptyValues rejects fields whose only value is an empty string, naming the first
+// offending field in alphabetical order so the error is stable
+func validateNoEmptyValues(results map[string][]string) *ParseError {
+	for _, field := range sortedFields(results) {
+		if values := results[field]; len(values) == 1 && values[0] == "" {
+			return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" cannot be empty`, field)}
+		}
+	}
+	return nil
+}
+
+// sortedFields returns the fields of results in alphabetical order
+func sortedFields[V any](results map[string][]V) []string {
+	fields := make([]string, 0, len(results))
+	for field := range results {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// transformValues replaces every value with the result of transform, in alphabetical order of
+// field so the first error returned is stable. A *ParseError from transform is returned as is,
+// any other error is wrapped in a 400 naming the field.
+func transformValues(results map[string][]string, transform func(field, value string) (string, error)) *ParseError {
+	for _, field := range sortedFields(results) {
+		for i, value := range results[field] {
+			transformed, err := transform(field, value)
+			if err != nil {
+				var pe *ParseError
+				if errors.As(err, &pe) {
+					return pe
+				}
+				return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`Form field "%s" has an invalid value`, field), Err: err}
+			}
+			results[field][i] = transformed
+		}
+	}
+	return nil
+}
+
+// validateContentLength checks the request declares a Content-Length no bigger than maxSize,
+// chunked requests of unknown length are rejected with a 411
+func validateContentLength(r *http.Request, maxSize int64) *ParseError {
+	if r.ContentLength < 0 {
+		return &ParseError{Status: http.StatusLengthRequired, Msg: "Content-Length header is required"}
+	}
+	if r.ContentLength > maxSize {
+		return &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}
+	}
+	return nil
+}
@@ -0,0 +1,470 @@
+package formhandler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRejectUnsafeKeyRunes(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.RejectUnsafeKeyRunes = true
+
+	var keyTests = []struct {
+		testName      string
+		parts         []multipartPart
+		expectedError bool
+	}{
+		{"plain names", []multipartPart{{field: "name", value: "charlie"}, {field: "fïle", fileName: "a.txt", value: "a"}}, false},
+		{"zero width space", []multipartPart{{field: "ad\u200bmin", value: "true"}}, true},
+		{"bidirectional override", []multipartPart{{field: "\u202eemail", value: "a@example.com"}}, true},
+		{"control character", []multipartPart{{field: "name\x07", value: "charlie"}}, true},
+		{"file field", []multipartPart{{field: "up\u200dload", fileName: "a.txt", value: "a"}}, true},
+	}
+
+	for _, tt := range keyTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructMultipartFormParts(tt.parts)
+			assert.NoError(t, err)
+
+			_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+			if tt.expectedError {
+				var pe *ParseError
+				assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+				assert.Equal(t, http.StatusBadRequest, pe.Status)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAllowedFields(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.AllowedFields = []string{"name", "avatar"}
+
+	var allowedFieldsTests = []struct {
+		testName      string
+		parts         []multipartPart
+		expectedError string
+	}{
+		{"allowed fields", []multipartPart{{field: "name", value: "charlie"}, {field: "avatar", fileName: "a.png", value: "a"}}, ""},
+		{"empty unknown field is dropped first", []multipartPart{{field: "name", value: "charlie"}, {field: "admin", value: ""}}, ""},
+		{"unknown value fields", []multipartPart{{field: "name", value: "charlie"}, {field: "role", value: "x"}, {field: "admin", value: "true"}}, `Form contains unknown fields "admin", "role"`},
+		{"unknown file field", []multipartPart{{field: "upload", fileName: "a.txt", value: "a"}}, `Form contains unknown fields "upload"`},
+	}
+
+	for _, tt := range allowedFieldsTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructMultipartFormParts(tt.parts)
+			assert.NoError(t, err)
+
+			_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+			if tt.expectedError != "" {
+				var pe *ParseError
+				assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+				assert.Equal(t, http.StatusBadRequest, pe.Status)
+				assert.Equal(t, tt.expectedError, pe.Msg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	r, err := constructJSONEncodedForm(`{"name": "charlie", "isAdmin": "true"}`)
+	assert.NoError(t, err)
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.Error(t, err)
+}
+
+func TestMaxFields(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.MaxFields = 2
+
+	jsonRequest := func(body string) func() (*http.Request, error) {
+		return func() (*http.Request, error) { return constructJSONEncodedForm(body) }
+	}
+	urlEncodedRequest := func(values url.Values) func() (*http.Request, error) {
+		return func() (*http.Request, error) { return constructURLEncodedForm(values) }
+	}
+	multipartRequest := func(parts ...multipartPart) func() (*http.Request, error) {
+		return func() (*http.Request, error) { return constructMultipartFormParts(parts) }
+	}
+
+	var maxFieldsTests = []struct {
+		testName      string
+		request       func() (*http.Request, error)
+		expectedError bool
+	}{
+		{"JSON within limit", jsonRequest(`{"a":"1","b":["2","3"]}`), false},
+		{"JSON over limit", jsonRequest(`{"a":"1","b":"2","c":"3"}`), true},
+		{"URL encoded within limit", urlEncodedRequest(url.Values{"a": {"1", "2"}, "b": {"3"}}), false},
+		{"URL encoded empty fields not counted", urlEncodedRequest(url.Values{"a": {"1"}, "b": {"2"}, "c": {""}}), false},
+		{"URL encoded over limit", urlEncodedRequest(url.Values{"a": {"1"}, "b": {"2"}, "c": {"3"}}), true},
+		{"multipart repeated file field", multipartRequest(
+			multipartPart{field: "a", value: "1"},
+			multipartPart{field: "file1", fileName: "one.txt", value: "one"},
+			multipartPart{field: "file1", fileName: "two.txt", value: "two"},
+		), false},
+		{"multipart over limit", multipartRequest(
+			multipartPart{field: "a", value: "1"},
+			multipartPart{field: "file1", fileName: "one.txt", value: "one"},
+			multipartPart{field: "file2", fileName: "two.txt", value: "two"},
+		), true},
+	}
+
+	for _, tt := range maxFieldsTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := tt.request()
+			assert.NoError(t, err)
+
+			_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+			if tt.expectedError {
+				var pe *ParseError
+				assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+				assert.Equal(t, http.StatusBadRequest, pe.Status)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRequireContentLength(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.RequireContentLength = true
+	cfg.MaxFormWithFilesSize = 1024
+
+	var contentLengthTests = []struct {
+		testName       string
+		contentLength  func(actual int64) int64
+		expectedStatus int
+	}{
+		{"declared length", func(actual int64) int64 { return actual }, 0},
+		{"chunked", func(int64) int64 { return -1 }, http.StatusLengthRequired},
+		{"declared over limit", func(int64) int64 { return 2048 }, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range contentLengthTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructMultipartFormParts([]multipartPart{{field: "field1", value: "value1"}})
+			assert.NoError(t, err)
+			r.ContentLength = tt.contentLength(r.ContentLength)
+
+			_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+			if tt.expectedStatus != 0 {
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, tt.expectedStatus, pe.Status)
+					assert.Zero(t, pe.BodySize, "no body should be read before the Content-Length is checked")
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMaxFiles(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.MaxFiles = 2
+
+	var maxFilesTests = []struct {
+		testName      string
+		parts         []multipartPart
+		expectedError bool
+	}{
+		{"within limit", []multipartPart{
+			{field: "a", value: "1"},
+			{field: "file1", fileName: "one.txt", value: "one"},
+			{field: "file2", fileName: "two.txt", value: "two"},
+		}, false},
+		{"over limit in one field", []multipartPart{
+			{field: "file1", fileName: "one.txt", value: "one"},
+			{field: "file1", fileName: "two.txt", value: "two"},
+			{field: "file1", fileName: "three.txt", value: "three"},
+		}, true},
+		{"over limit across fields", []multipartPart{
+			{field: "file1", fileName: "one.txt", value: "one"},
+			{field: "file2", fileName: "two.txt", value: "two"},
+			{field: "file3", fileName: "three.txt", value: "three"},
+		}, true},
+	}
+
+	for _, tt := range maxFilesTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructMultipartFormParts(tt.parts)
+			assert.NoError(t, err)
+
+			_, files, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+			if tt.expectedError {
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, http.StatusBadRequest, pe.Status)
+					assert.Equal(t, "Form contains 3 files, more than the limit of 2", pe.Msg)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, files, 2)
+			}
+		})
+	}
+}
+
+func TestMaxArrayLen(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.MaxArrayLen = 2
+
+	var maxArrayLenTests = []struct {
+		testName             string
+		body                 string
+		expectedValuesOutput map[string][]string
+		expectedError        bool
+	}{
+		{"within limit", `{"a":["1","2"],"b":"3"}`, map[string][]string{"a": {"1", "2"}, "b": {"3"}}, false},
+		{"over limit", `{"a":["1","2","3"]}`, nil, true},
+	}
+
+	for _, tt := range maxArrayLenTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := constructJSONEncodedForm(tt.body)
+			assert.NoError(t, err)
+
+			results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+			if tt.expectedError {
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, http.StatusBadRequest, pe.Status)
+					assert.Equal(t, `JSON object contains invalid array for field "a", arrays can contain at most 2 values`, pe.Msg)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMaxValueLength(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.MaxValueLength = 5
+
+	jsonRequest := func(body string) func() (*http.Request, error) {
+		return func() (*http.Request, error) { return constructJSONEncodedForm(body) }
+	}
+	urlEncodedRequest := func(values url.Values) func() (*http.Request, error) {
+		return func() (*http.Request, error) { return constructURLEncodedForm(values) }
+	}
+	multipartRequest := func(parts ...multipartPart) func() (*http.Request, error) {
+		return func() (*http.Request, error) { return constructMultipartFormParts(parts) }
+	}
+
+	var maxValueLengthTests = []struct {
+		testName      string
+		request       func() (*http.Request, error)
+		expectedField string
+	}{
+		{"JSON within limit", jsonRequest(`{"a":"12345","b":["1","2"]}`), ""},
+		{"JSON array value over limit", jsonRequest(`{"a":"1","b":["2","123456"]}`), "b"},
+		{"URL encoded within limit", urlEncodedRequest(url.Values{"a": {"12345", "1"}}), ""},
+		{"URL encoded over limit", urlEncodedRequest(url.Values{"a": {"1"}, "b": {"1", "123456"}}), "b"},
+		{"multipart over limit", multipartRequest(multipartPart{field: "a", value: "123456"}), "a"},
+		{"multipart files not checked", multipartRequest(multipartPart{field: "file1", fileName: "one.txt", value: "long file content"}), ""},
+		{"first field named", jsonRequest(`{"d":"123456","c":"123456"}`), "c"},
+	}
+
+	for _, tt := range maxValueLengthTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := tt.request()
+			assert.NoError(t, err)
+
+			_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+			if tt.expectedField != "" {
+				var pe *ParseError
+				assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+				assert.Equal(t, http.StatusBadRequest, pe.Status)
+				assert.Contains(t, pe.Msg, `"`+tt.expectedField+`"`)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRejectNullBytes(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.RejectNullBytes = true
+
+	var nullByteTests = []struct {
+		testName      string
+		request       func() (*http.Request, error)
+		expectedError bool
+	}{
+		{"JSON without null bytes", func() (*http.Request, error) {
+			return constructJSONEncodedForm(`{"a":"1","b":["2","3"]}`)
+		}, false},
+		{"JSON value", func() (*http.Request, error) {
+			return constructJSONEncodedForm(`{"a":"1","b":"nul\u0000byte"}`)
+		}, true},
+		{"JSON array value", func() (*http.Request, error) {
+			return constructJSONEncodedForm(`{"a":"1","b":["2","\u0000"]}`)
+		}, true},
+		{"multipart without null bytes", func() (*http.Request, error) {
+			return constructMultipartFormParts([]multipartPart{{field: "a", value: "1"}, {field: "b", value: "2"}})
+		}, false},
+		{"multipart value", func() (*http.Request, error) {
+			return constructMultipartFormParts([]multipartPart{{field: "a", value: "1"}, {field: "b", value: "nul\x00byte"}})
+		}, true},
+		{"URL encoded value", func() (*http.Request, error) {
+			return constructURLEncodedForm(url.Values{"a": {"1"}, "b": {"\x00"}})
+		}, true},
+	}
+
+	for _, tt := range nullByteTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := tt.request()
+			assert.NoError(t, err)
+
+			results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+			if tt.expectedError {
+				assert.Nil(t, results)
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, http.StatusBadRequest, pe.Status)
+					assert.Equal(t, `Form field "b" contains a null byte`, pe.Msg)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRequireValidUTF8(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.RequireValidUTF8 = true
+
+	var utf8Tests = []struct {
+		testName      string
+		request       func() (*http.Request, error)
+		expectedError bool
+	}{
+		{"multipart valid UTF-8", func() (*http.Request, error) {
+			return constructMultipartFormParts([]multipartPart{{field: "a", value: "h\u00e9llo"}, {field: "b", value: "\u4e16\u754c"}})
+		}, false},
+		{"multipart invalid byte", func() (*http.Request, error) {
+			return constructMultipartFormParts([]multipartPart{{field: "a", value: "1"}, {field: "b", value: "inv\xffalid"}})
+		}, true},
+		{"multipart truncated sequence", func() (*http.Request, error) {
+			return constructMultipartFormParts([]multipartPart{{field: "a", value: "1"}, {field: "b", value: "\xe4\xb8"}})
+		}, true},
+		{"multipart invalid value in repeated field", func() (*http.Request, error) {
+			return constructMultipartFormParts([]multipartPart{{field: "b", value: "valid"}, {field: "b", value: "\xc0\xaf"}})
+		}, true},
+		{"URL encoded invalid byte", func() (*http.Request, error) {
+			return constructURLEncodedForm(url.Values{"a": {"1"}, "b": {"\xff"}})
+		}, true},
+	}
+
+	for _, tt := range utf8Tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := tt.request()
+			assert.NoError(t, err)
+
+			results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+			if tt.expectedError {
+				assert.Nil(t, results)
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, http.StatusBadRequest, pe.Status)
+					assert.Equal(t, `Form field "b" contains invalid UTF-8`, pe.Msg)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTrimSpace(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.TrimSpace = true
+
+	var trimSpaceTests = []struct {
+		testName             string
+		request              func() (*http.Request, error)
+		expectedValuesOutput map[string][]string
+		expectedError        bool
+	}{
+		{"URL encoded", func() (*http.Request, error) {
+			return constructURLEncodedForm(url.Values{"a": {" 1 ", "\t2\n"}, "b": {"   "}})
+		}, map[string][]string{"a": {"1", "2"}}, false},
+		{"multipart", func() (*http.Request, error) {
+			return constructMultipartFormParts([]multipartPart{{field: "a", value: " 1 "}, {field: "b", value: " "}})
+		}, map[string][]string{"a": {"1"}}, false},
+		{"JSON", func() (*http.Request, error) {
+			return constructJSONEncodedForm(`{"a":" 1 ","b":[" 2","3 "]}`)
+		}, map[string][]string{"a": {"1"}, "b": {"2", "3"}}, false},
+		{"JSON whitespace only value", func() (*http.Request, error) {
+			return constructJSONEncodedForm(`{"a":"1","b":"  "}`)
+		}, nil, true},
+	}
+
+	for _, tt := range trimSpaceTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			r, err := tt.request()
+			assert.NoError(t, err)
+
+			results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.expectedValuesOutput, results)
+			if tt.expectedError {
+				var pe *ParseError
+				if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+					assert.Equal(t, http.StatusBadRequest, pe.Status)
+					assert.Equal(t, `Form field "b" cannot be empty`, pe.Msg)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValueTransform(t *testing.T) {
+	errControl := errors.New("control character")
+	cfg := defaultConfig()
+	cfg.ValueTransform = func(field, value string) (string, error) {
+		if strings.ContainsRune(value, '\x00') {
+			return "", errControl
+		}
+		return strings.TrimSpace(value), nil
+	}
+
+	r, err := constructJSONEncodedForm(`{"name":"  charlie ","tags":[" a","b "]}`)
+	assert.NoError(t, err)
+	results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"name": {"charlie"}, "tags": {"a", "b"}}, results)
+
+	// values transformed to empty strings are removed like other empty form fields
+	r, err = constructURLEncodedForm(url.Values{"name": {" charlie"}, "blank": {"   "}})
+	assert.NoError(t, err)
+	results, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"name": {"charlie"}}, results)
+
+	r, err = constructMultipartFormParts([]multipartPart{{field: "name", value: "char\x00lie"}})
+	assert.NoError(t, err)
+	_, _, err = cfg.GetFormContent(httptest.NewRecorder(), r)
+	var pe *ParseError
+	if assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError") {
+		assert.Equal(t, http.StatusBadRequest, pe.Status)
+		assert.Equal(t, `Form field "name" has an invalid value`, pe.Msg)
+		assert.True(t, errors.Is(err, errControl))
+	}
+}
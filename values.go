@@ -0,0 +1,21 @@
+package formhandler
+
+// First returns the first value of key in results, and whether key has a value
+func First(results map[string][]string, key string) (string, bool) {
+	values := results[key]
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// Get returns the first value of key in results, or an empty string if key has no value
+func Get(results map[string][]string, key string) string {
+	value, _ := First(results, key)
+	return value
+}
+
+// Has reports whether key has a value in results
+func Has(results map[string][]string, key string) bool {
+	return len(results[key]) > 0
+}
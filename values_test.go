@@ -0,0 +1,37 @@
+package formhandler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueAccessors(t *testing.T) {
+	results := map[string][]string{
+		"field1": {"value1", "value2"},
+		"empty":  {},
+	}
+
+	var accessorTests = []struct {
+		testName      string
+		key           string
+		expectedValue string
+		expectedOK    bool
+	}{
+		{"first of many values", "field1", "value1", true},
+		{"field without values", "empty", "", false},
+		{"missing field", "missing", "", false},
+	}
+
+	for _, tt := range accessorTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			value, ok := First(results, tt.key)
+			assert.Equal(t, tt.expectedValue, value)
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedValue, Get(results, tt.key))
+			assert.Equal(t, tt.expectedOK, Has(results, tt.key))
+		})
+	}
+
+	assert.Equal(t, "", Get(nil, "field1"))
+}
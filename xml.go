@@ -0,0 +1,149 @@
+package formhandler
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	headerValApplicationXML = "application/xml"
+	headerValTextXML        = "text/xml"
+)
+
+// parseApplicationXML parses a flat XML document, where each child of the root element is a
+// form field, into results. Repeated child elements become multiple values of the same field:
+//
+//	<form><name>charlie</name><choice>1</choice><choice>4</choice></form>
+//
+// The same rules as JSON apply, values must be non-empty text, unless KeepEmptyFields is set, and
+// nested elements are rejected.
+func (cfg Config) parseApplicationXML(reader io.Reader) (results map[string][]string, err *ParseError) {
+	dec := xml.NewDecoder(reader)
+
+	root, parseErr := nextXMLElement(dec)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	results = make(map[string][]string)
+	for {
+		token, tokenErr := dec.Token()
+		if tokenErr != nil {
+			return nil, xmlTokenError(tokenErr)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			field := t.Name.Local
+			value, parseErr := readXMLValue(dec, field, cfg.KeepEmptyFields)
+			if parseErr != nil {
+				return nil, parseErr
+			}
+			results[field] = append(results[field], value)
+
+		case xml.CharData:
+			if len(strings.TrimSpace(string(t))) > 0 {
+				return nil, &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`XML element <%s> must only contain field elements`, root.Name.Local)}
+			}
+
+		case xml.EndElement:
+			if len(results) == 0 {
+				return nil, &ParseError{Status: http.StatusBadRequest, Msg: `XML document contains no fields`}
+			}
+			if parseErr := expectXMLEnd(dec); parseErr != nil {
+				return nil, parseErr
+			}
+			return results, nil
+		}
+	}
+}
+
+// nextXMLElement returns the next start element, skipping the XML declaration, comments and whitespace
+func nextXMLElement(dec *xml.Decoder) (xml.StartElement, *ParseError) {
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return xml.StartElement{}, xmlTokenError(err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			return t, nil
+		case xml.CharData:
+			if len(strings.TrimSpace(string(t))) > 0 {
+				return xml.StartElement{}, &ParseError{Status: http.StatusBadRequest, Msg: "Request body contains badly-formed XML"}
+			}
+		}
+	}
+}
+
+// expectXMLEnd checks nothing other than comments and whitespace follow the root element
+func expectXMLEnd(dec *xml.Decoder) *ParseError {
+	for {
+		token, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return xmlTokenError(err)
+		}
+
+		switch t := token.(type) {
+		case xml.Comment:
+		case xml.CharData:
+			if len(strings.TrimSpace(string(t))) > 0 {
+				return &ParseError{Status: http.StatusBadRequest, Msg: "Request body must only contain a single XML document"}
+			}
+		default:
+			return &ParseError{Status: http.StatusBadRequest, Msg: "Request body must only contain a single XML document"}
+		}
+	}
+}
+
+// readXMLValue reads the text content of a field element up to its end element, rejecting an
+// empty element unless keepEmpty is set
+func readXMLValue(dec *xml.Decoder, field string, keepEmpty bool) (string, *ParseError) {
+	var sb strings.Builder
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return "", xmlTokenError(err)
+		}
+
+		switch t := token.(type) {
+		case xml.CharData:
+			sb.Write(t)
+
+		case xml.StartElement:
+			return "", &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`XML document contains invalid value for field "%s", values cannot contain nested elements`, field)}
+
+		case xml.EndElement:
+			if sb.Len() == 0 && !keepEmpty {
+				return "", &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(`XML document contains invalid value for field "%s", cannot use an empty value`, field)}
+			}
+			return sb.String(), nil
+		}
+	}
+}
+
+func xmlTokenError(err error) *ParseError {
+	var syntaxError *xml.SyntaxError
+
+	switch {
+	case errors.As(err, &syntaxError):
+		return &ParseError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("Request body contains badly-formed XML (at line %d)", syntaxError.Line)}
+
+	case errors.Is(err, io.EOF):
+		return &ParseError{Status: http.StatusBadRequest, Msg: "Request body must not be empty"}
+
+	case isRequestTooLarge(err):
+		return &ParseError{Status: http.StatusRequestEntityTooLarge, Msg: "Request body too large"}
+
+	default:
+		return &ParseError{Status: http.StatusBadRequest, Msg: "Request body contains badly-formed XML"}
+	}
+}
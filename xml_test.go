@@ -0,0 +1,87 @@
+package formhandler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFormContent_XMLEncoded(t *testing.T) {
+	var formContentTests = []struct {
+		testName             string
+		body                 string
+		expectedValuesOutput map[string][]string
+		expectedStatus       int
+	}{
+		{
+			"1 valid field",
+			`<form><field1>value1</field1></form>`,
+			map[string][]string{"field1": {"value1"}},
+			0,
+		},
+		{
+			"repeated fields with declaration and whitespace",
+			`<?xml version="1.0" encoding="UTF-8"?>
+			<form>
+				<field1>value1</field1>
+				<field2>value21</field2>
+				<field2>value22</field2>
+			</form>
+			`,
+			map[string][]string{"field1": {"value1"}, "field2": {"value21", "value22"}},
+			0,
+		},
+		{
+			"CDATA value",
+			`<form><field1><![CDATA[<b>bold</b>]]></field1></form>`,
+			map[string][]string{"field1": {"<b>bold</b>"}},
+			0,
+		},
+		{"empty body", ``, nil, http.StatusBadRequest},
+		{"no fields", `<form></form>`, nil, http.StatusBadRequest},
+		{"empty value", `<form><field1></field1></form>`, nil, http.StatusBadRequest},
+		{"nested element", `<form><field1><child>value</child></field1></form>`, nil, http.StatusBadRequest},
+		{"text in root", `<form>text<field1>value1</field1></form>`, nil, http.StatusBadRequest},
+		{"unclosed element", `<form><field1>value1</field1>`, nil, http.StatusBadRequest},
+		{"multiple documents", `<form><field1>value1</field1></form><form></form>`, nil, http.StatusBadRequest},
+		{"not xml", `hello world`, nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range formContentTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			for _, contentType := range []string{"application/xml", "text/xml"} {
+				r, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+				assert.NoError(t, err)
+				r.Header.Set("Content-Type", contentType)
+
+				results, files, err := GetFormContent(httptest.NewRecorder(), r)
+				assert.Equal(t, tt.expectedValuesOutput, results, "unexpected parsed form results")
+				assert.Empty(t, files)
+				if tt.expectedStatus == 0 {
+					assert.NoError(t, err)
+				} else {
+					var pe *ParseError
+					assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+					assert.Equal(t, tt.expectedStatus, pe.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestGetFormContent_XMLKeepEmptyFields(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.KeepEmptyFields = true
+
+	r, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(`<form><field1>value1</field1><field2></field2><field3/></form>`))
+	assert.NoError(t, err)
+	r.Header.Set("Content-Type", "application/xml")
+
+	results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"field1": {"value1"}, "field2": {""}, "field3": {""}}, results)
+}
@@ -0,0 +1,17 @@
+module github.com/charlesworth/formhandler/yaml
+
+go 1.18
+
+require (
+	github.com/charlesworth/formhandler v0.0.0-20261016180954-9f6b316578ae
+	github.com/stretchr/testify v1.6.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
+
+replace github.com/charlesworth/formhandler => ../
@@ -0,0 +1,111 @@
+// Package yaml adds application/yaml and text/yaml request bodies to formhandler. Importing it
+// registers the parser:
+//
+//	import _ "github.com/charlesworth/formhandler/yaml"
+//
+// A body must be a single YAML mapping following the same value rules as JSON: each key must
+// have a non-empty scalar or a sequence of scalars, and nested mappings are rejected. Scalars are
+// kept as written, so "birthday: 2001-12-14" is the value "2001-12-14", while unquoted numbers
+// and booleans are only accepted with CoerceScalars.
+//
+// YAML bodies used to be parsed by the formhandler package itself. They're now rejected with a
+// 415 unless this package is imported, so programs accepting YAML must add the import above.
+package yaml
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/charlesworth/formhandler"
+	"gopkg.in/yaml.v3"
+)
+
+// The Content-Types of the request bodies parsed by this package
+const (
+	ContentTypeApplication = "application/yaml"
+	ContentTypeText        = "text/yaml"
+)
+
+func init() {
+	formhandler.RegisterBodyParser(ContentTypeApplication, parse)
+	formhandler.RegisterBodyParser(ContentTypeText, parse)
+}
+
+// parse parses a single YAML mapping, applying the same value rules as JSON. Scalars are
+// kept as the text they were written as, so values such as dates aren't reformatted, with
+// numbers and booleans only accepted when CoerceScalars is set.
+func parse(cfg formhandler.Config, r *http.Request, body io.Reader) (map[string][]string, *formhandler.ParseError) {
+	dec := yaml.NewDecoder(body)
+	var document yaml.Node
+	decodeErr := dec.Decode(&document)
+	if errors.Is(decodeErr, io.EOF) {
+		return nil, &formhandler.ParseError{Status: http.StatusBadRequest, Msg: "Request body must not be empty"}
+	}
+	if decodeErr != nil {
+		return nil, &formhandler.ParseError{Status: http.StatusBadRequest, Msg: "Request body contains badly-formed YAML"}
+	}
+
+	var trailing yaml.Node
+	if secondDecodeErr := dec.Decode(&trailing); secondDecodeErr != io.EOF {
+		return nil, &formhandler.ParseError{Status: http.StatusBadRequest, Msg: "Request body must only contain a single YAML mapping"}
+	}
+
+	mapping := resolveAlias(&document)
+	if mapping.Kind == yaml.DocumentNode && len(mapping.Content) == 1 {
+		mapping = resolveAlias(mapping.Content[0])
+	}
+	if mapping.Kind != yaml.MappingNode {
+		return nil, &formhandler.ParseError{Status: http.StatusBadRequest, Msg: "Request body must contain a YAML mapping"}
+	}
+
+	yamlContent := make(map[string]interface{}, len(mapping.Content)/2)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := resolveAlias(mapping.Content[i]), resolveAlias(mapping.Content[i+1])
+		if key.Kind != yaml.ScalarNode {
+			return nil, &formhandler.ParseError{Status: http.StatusBadRequest, Msg: "Request body must contain a YAML mapping with scalar keys"}
+		}
+
+		switch value.Kind {
+		case yaml.ScalarNode:
+			yamlContent[key.Value] = scalar(value)
+		case yaml.SequenceNode:
+			values := make([]interface{}, 0, len(value.Content))
+			for _, item := range value.Content {
+				if item = resolveAlias(item); item.Kind == yaml.ScalarNode {
+					values = append(values, scalar(item))
+				} else {
+					values = append(values, item)
+				}
+			}
+			yamlContent[key.Value] = values
+		default:
+			// nested mappings are left as nodes, which the value rules reject
+			yamlContent[key.Value] = value
+		}
+	}
+
+	return cfg.FlattenMap(yamlContent, "YAML mapping")
+}
+
+// scalar returns the value of a scalar node, as a string unless it's a null, number or
+// boolean, which are decoded so CoerceScalars can apply to them
+func scalar(node *yaml.Node) interface{} {
+	switch node.ShortTag() {
+	case "!!null":
+		return nil
+	case "!!int", "!!float", "!!bool":
+		var value interface{}
+		if node.Decode(&value) == nil {
+			return value
+		}
+	}
+	return node.Value
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
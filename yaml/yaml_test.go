@@ -0,0 +1,85 @@
+package yaml
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/charlesworth/formhandler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	var formContentTests = []struct {
+		testName             string
+		body                 string
+		coerceScalars        bool
+		expectedValuesOutput map[string][]string
+		expectedStatus       int
+	}{
+		{
+			"string and sequence fields",
+			"field1: value1\nfield2:\n  - value21\n  - value22\n",
+			false,
+			map[string][]string{"field1": {"value1"}, "field2": {"value21", "value22"}},
+			0,
+		},
+		{
+			"scalars kept as written",
+			"birthday: 2001-12-14\nquoted: \"30\"\nflow: [a, 'b']\n",
+			false,
+			map[string][]string{"birthday": {"2001-12-14"}, "quoted": {"30"}, "flow": {"a", "b"}},
+			0,
+		},
+		{
+			"coerced scalars",
+			"age: 30\nheight: 1.5\nmember: true\nchoices: [1, two]\n",
+			true,
+			map[string][]string{"age": {"30"}, "height": {"1.5"}, "member": {"true"}, "choices": {"1", "two"}},
+			0,
+		},
+		{
+			"anchors and aliases",
+			"first: &name charlie\nsecond: *name\n",
+			false,
+			map[string][]string{"first": {"charlie"}, "second": {"charlie"}},
+			0,
+		},
+		{"integer without coercion", "age: 30\n", false, nil, http.StatusBadRequest},
+		{"null", "field1: ~\n", true, nil, http.StatusBadRequest},
+		{"empty string", "field1: ''\n", false, nil, http.StatusBadRequest},
+		{"nested mapping", "field1:\n  a: b\n", false, nil, http.StatusBadRequest},
+		{"nested sequence", "field1: [[a]]\n", false, nil, http.StatusBadRequest},
+		{"empty mapping", "{}\n", false, nil, http.StatusBadRequest},
+		{"not a mapping", "- a\n", false, nil, http.StatusBadRequest},
+		{"multiple documents", "a: a\n---\nb: b\n", false, nil, http.StatusBadRequest},
+		{"empty body", "", false, nil, http.StatusBadRequest},
+		{"malformed", "field1: [a\n", false, nil, http.StatusBadRequest},
+		{"too large", "field1: " + strings.Repeat("a", 2_000_000) + "\n", false, nil, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range formContentTests {
+		t.Run(tt.testName, func(t *testing.T) {
+			var cfg formhandler.Config
+			cfg.CoerceScalars = tt.coerceScalars
+
+			for _, contentType := range []string{"application/yaml", "text/yaml"} {
+				r, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+				assert.NoError(t, err)
+				r.Header.Set("Content-Type", contentType)
+
+				results, _, err := cfg.GetFormContent(httptest.NewRecorder(), r)
+				assert.Equal(t, tt.expectedValuesOutput, results)
+				if tt.expectedStatus == 0 {
+					assert.NoError(t, err)
+				} else {
+					var pe *formhandler.ParseError
+					assert.True(t, errors.As(err, &pe), "Returned error is not base type ParseError")
+					assert.Equal(t, tt.expectedStatus, pe.Status)
+				}
+			}
+		})
+	}
+}